@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"cloud.google.com/go/storage"
+	"github.com/lizrice/zwiftpower/zp"
+)
+
+// gcsCache is a zp.Cache that keeps each entry as an object in a Cloud Storage bucket,
+// so that cached responses survive between Cloud Run instances
+type gcsCache struct {
+	bkt    *storage.BucketHandle
+	prefix string
+}
+
+func (g gcsCache) object(key string) *storage.ObjectHandle {
+	sum := sha256.Sum256([]byte(key))
+	return g.bkt.Object(g.prefix + hex.EncodeToString(sum[:]) + ".json")
+}
+
+func (g gcsCache) Get(ctx context.Context, key string) (*zp.CacheEntry, error) {
+	r, err := g.object(key).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var entry zp.CacheEntry
+	if err := json.NewDecoder(r).Decode(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (g gcsCache) Put(ctx context.Context, key string, entry *zp.CacheEntry) error {
+	w := g.object(key).NewWriter(ctx)
+	w.ContentType = "application/json"
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
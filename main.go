@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/lizrice/zwiftpower/zp"
 	"github.com/spf13/cobra"
@@ -16,11 +22,48 @@ import (
 )
 
 var (
-	Filename         string
-	SpreadsheetID    string
-	SpreadsheetSheet string
-	Limit            int
-	storageClient    *storage.Client
+	Filename            string
+	SpreadsheetID       string
+	SpreadsheetSheet    string
+	Limit               int
+	Concurrency         int
+	RateLimit           float64
+	Retries             int
+	MaxFailures         int
+	Timeout             time.Duration
+	CacheDir            string
+	CacheTTL            time.Duration
+	NoCache             bool
+	Refresh             bool
+	Windows             []int
+	Periods             []string
+	Durations           []time.Duration
+	AsOf                string
+	EventSets           []string
+	TTKeywords          []string
+	Sources             string
+	Stats               []string
+	CPModel             string
+	TrendWeeks          int
+	Categories          bool
+	UpgradeMargin       float64
+	Scores              bool
+	ScoreBands          string
+	ScoreCategories     []string
+	Weights             bool
+	WeightBasis         string
+	WeightDrop          float64
+	SnapshotFile        string
+	ChangesFile         string
+	ChangesSheet        string
+	HistoryFile         string
+	HistorySheet        string
+	WeightChange        float64
+	BaseURL             string
+	CloudFrontPolicy    string
+	CloudFrontSignature string
+	CloudFrontKeyPairId string
+	storageClient       *storage.Client
 )
 
 const (
@@ -29,12 +72,46 @@ const (
 	env_SpreadsheetID       = "SPREADSHEET_ID"
 	env_SpreadsheetSheet    = "SPREADSHEET_SHEET"
 	env_Limit               = "LIMIT"
+	env_Concurrency         = "CONCURRENCY"
+	env_RateLimit           = "RATELIMIT"
+	env_Retries             = "RETRIES"
+	env_MaxFailures         = "MAXFAILURES"
+	env_Timeout             = "TIMEOUT"
+	env_CacheDir            = "CACHE_DIR"
+	env_CacheTTL            = "CACHE_TTL"
+	env_Windows             = "WINDOWS"
+	env_Periods             = "PERIODS"
+	env_Durations           = "DURATIONS"
+	env_EventSets           = "EVENT_SETS"
+	env_TTKeywords          = "TT_KEYWORDS"
+	env_Sources             = "SOURCES"
+	env_Stats               = "STATS"
+	env_CPModel             = "CP_MODEL"
+	env_TrendWeeks          = "TREND_WEEKS"
+	env_Categories          = "CATEGORIES"
+	env_UpgradeMargin       = "UPGRADE_MARGIN"
+	env_Scores              = "SCORES"
+	env_ScoreBands          = "SCORE_BANDS"
+	env_ScoreCategories     = "SCORE_CATEGORIES"
+	env_Weights             = "WEIGHTS"
+	env_WeightBasis         = "WEIGHT_BASIS"
+	env_WeightDrop          = "WEIGHT_DROP"
+	env_Snapshot            = "SNAPSHOT"
+	env_ChangesFile         = "CHANGES_FILE"
+	env_ChangesSheet        = "CHANGES_SHEET"
+	env_HistoryFile         = "HISTORY_FILE"
+	env_HistorySheet        = "HISTORY_SHEET"
+	env_WeightChange        = "WEIGHT_CHANGE"
 	env_Port                = "PORT"
+	env_BaseURL             = "BASEURL"
 	env_CloudFrontSignature = "CLOUDFRONTSIGNATURE"
 	env_CloudFrontPolicy    = "CLOUDFRONTPOLICY"
 	env_CloudFrontKeyPairId = "CLOUDFRONTKEYPAIRID"
 )
 
+// bucketName is the Cloud Storage bucket used when running as a service
+const bucketName = "revo-rider-aardvark"
+
 func getID(args []string, defaultID int) (id int) {
 	id = defaultID
 	if len(args) >= 1 {
@@ -48,6 +125,140 @@ func getID(args []string, defaultID int) (id int) {
 	return id
 }
 
+// parseDate reads a YYYY-MM-DD date in local time. An empty string gives the zero time.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+// envInt reads an integer from the environment variable name, if it's set
+func envInt(name string, defaultVal int) int {
+	s := os.Getenv(name)
+	if s == "" {
+		return defaultVal
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		log.Printf("Ignoring environment variable %s: %v", name, err)
+		return defaultVal
+	}
+	return v
+}
+
+// envString reads the environment variable name, if it's set
+func envString(name string, defaultVal string) string {
+	if s := os.Getenv(name); s != "" {
+		return s
+	}
+	return defaultVal
+}
+
+// envBool reads true or false from the environment variable name, if it's set
+func envBool(name string, defaultVal bool) bool {
+	s := os.Getenv(name)
+	if s == "" {
+		return defaultVal
+	}
+
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		log.Printf("Ignoring environment variable %s: %v", name, err)
+		return defaultVal
+	}
+	return v
+}
+
+// envFloat reads a number from the environment variable name, if it's set
+func envFloat(name string, defaultVal float64) float64 {
+	s := os.Getenv(name)
+	if s == "" {
+		return defaultVal
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		log.Printf("Ignoring environment variable %s: %v", name, err)
+		return defaultVal
+	}
+	return v
+}
+
+// envInts reads a comma-separated list of integers such as "30,60,90" from the
+// environment variable name, if it's set
+func envInts(name string, defaultVal []int) []int {
+	s := os.Getenv(name)
+	if s == "" {
+		return defaultVal
+	}
+
+	var v []int
+	for _, f := range strings.Split(s, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			log.Printf("Ignoring environment variable %s: %v", name, err)
+			return defaultVal
+		}
+		v = append(v, i)
+	}
+	return v
+}
+
+// envDuration reads a duration such as "90s" from the environment variable name, if it's set
+func envDuration(name string, defaultVal time.Duration) time.Duration {
+	s := os.Getenv(name)
+	if s == "" {
+		return defaultVal
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		log.Printf("Ignoring environment variable %s: %v", name, err)
+		return defaultVal
+	}
+	return v
+}
+
+// envStrings reads a comma-separated list from the environment variable name, if it's set
+func envStrings(name string) []string {
+	s := os.Getenv(name)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// envDurations reads a comma-separated list of durations such as "20m,10s" from the
+// environment variable name, if it's set
+func envDurations(name string, defaultVal []time.Duration) []time.Duration {
+	s := os.Getenv(name)
+	if s == "" {
+		return defaultVal
+	}
+
+	var v []time.Duration
+	for _, f := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(f))
+		if err != nil {
+			log.Printf("Ignoring environment variable %s: %v", name, err)
+			return defaultVal
+		}
+		v = append(v, d)
+	}
+	return v
+}
+
+// defaultDurations are zp.DefaultDurations as the durations flag expects them
+func defaultDurations() []time.Duration {
+	ds := make([]time.Duration, len(zp.DefaultDurations))
+	for i, d := range zp.DefaultDurations {
+		ds[i] = time.Duration(d.Seconds) * time.Second
+	}
+	return ds
+}
+
 func main() {
 	clubID, err := strconv.Atoi(os.Getenv(env_ClubID))
 	if err != nil {
@@ -91,50 +302,401 @@ func main() {
 		Run: func(cmd *cobra.Command, args []string) {
 			riderID := getID(args, 98588)
 
-			rider, err := zp.ImportRider(riderID)
+			client, err := newZPClient()
+			if err != nil {
+				fmt.Printf("Error getting client: %v", err)
+				os.Exit(1)
+			}
+
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			rider, err := client.ImportRiderContext(ctx, riderID)
+			if err != nil {
+				problem := describeError(err)
+				fmt.Fprintf(os.Stderr, "Error getting rider %d: %s\n%v\n", riderID, problem.message, err)
+				os.Exit(problem.exitCode)
+			}
+			l := layout()
+			l.BaseURL = client.BaseURL()
+			fmt.Printf("%v\n", l.Row(rider))
+		},
+	}
+
+	riderCmd.Flags().StringVar(&AsOf, "as-of", "", "Report the rider's data as it was at the end of this date (YYYY-MM-DD)")
+
+	var since, until string
+	var racesOnly bool
+	historyCmd := &cobra.Command{
+		Use:   "history [ID]",
+		Short: "Export every event for rider ID",
+		Run: func(cmd *cobra.Command, args []string) {
+			riderID := getID(args, 98588)
+
+			var filters []zp.EventFilter
+			from, err := parseDate(since)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Can't parse --since: %v\n", err)
+				os.Exit(1)
+			}
+			to, err := parseDate(until)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Can't parse --until: %v\n", err)
+				os.Exit(1)
+			}
+			if !to.IsZero() {
+				// Include the whole of the last day
+				to = to.AddDate(0, 0, 1)
+			}
+			filters = append(filters, zp.Between(from, to))
+			if racesOnly {
+				filters = append(filters, zp.RacesOnly())
+			}
+
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			err = RiderHistory(ctx, riderID, filters...)
 			if err != nil {
-				fmt.Printf("Error getting rider: %v", err)
+				problem := describeError(err)
+				fmt.Fprintf(os.Stderr, "Error getting history for rider %d: %s\n%v\n", riderID, problem.message, err)
+				os.Exit(problem.exitCode)
 			}
-			fmt.Printf("%v\n", rider.Strings())
 		},
 	}
+	historyCmd.Flags().StringVar(&since, "since", "", "Only include events on or after this date (YYYY-MM-DD)")
+	historyCmd.Flags().StringVar(&until, "until", "", "Only include events on or before this date (YYYY-MM-DD)")
+	historyCmd.Flags().BoolVar(&racesOnly, "races-only", false, "Only include races")
+	historyCmd.Flags().StringVar(&HistoryFile, "history-file", os.Getenv(env_HistoryFile), "File to write the rider's events to")
+	historyCmd.Flags().StringVar(&HistorySheet, "history-sheet", envString(env_HistorySheet, "History"), "Google sheets sheet name for the rider's events")
 
 	localCmd := &cobra.Command{
 		Use:   "zp [ID]",
 		Short: "Import data for club ID",
 		Run: func(cmd *cobra.Command, args []string) {
-			err := ImportTeam(clubID, Limit)
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			changes, failures, err := ImportTeam(ctx, clubID, Limit)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error getting ZwiftPower data for %d: %v", clubID, err)
-				os.Exit(1)
+				problem := describeError(err)
+				fmt.Fprintf(os.Stderr, "Error getting ZwiftPower data for %d: %s\n%v\n", clubID, problem.message, err)
+				os.Exit(problem.exitCode)
+			}
+
+			if snapshotting() {
+				reportChanges(os.Stderr, changes)
+			}
+
+			if failures != nil {
+				reportFailures(os.Stderr, failures)
+				if len(failures.Failures) > MaxFailures {
+					os.Exit(describeError(failures).exitCode)
+				}
 			}
 		},
 	}
 
-	var limit int
-	limitString := os.Getenv(env_Limit)
-	if limitString != "" {
-		limit, _ = strconv.Atoi(limitString)
-	}
+	localCmd.Flags().StringVar(&AsOf, "as-of", "", "Report the team's data as it was at the end of this date (YYYY-MM-DD)")
 
 	rootCmd.PersistentFlags().StringVarP(&Filename, "filename", "f", os.Getenv(env_Filename), "Output file name")
 	rootCmd.PersistentFlags().StringVarP(&SpreadsheetID, "spreadsheet", "s", os.Getenv(env_SpreadsheetID), "Google sheets ID")
 	rootCmd.PersistentFlags().StringVarP(&SpreadsheetSheet, "sheetname", "n", os.Getenv(env_SpreadsheetSheet), "Google sheets sheet name")
-	rootCmd.PersistentFlags().StringVarP(&zp.CloudFrontPolicy, "CloudFrontPolicy", "a", os.Getenv(env_CloudFrontPolicy), "CloudFrontPolicy")
-	rootCmd.PersistentFlags().StringVarP(&zp.CloudFrontSignature, "CloudFrontSignature", "b", os.Getenv(env_CloudFrontSignature), "CloudFrontSignature")
-	rootCmd.PersistentFlags().StringVarP(&zp.CloudFrontKeyPairId, "CloudFrontKeyPairId", "c", os.Getenv(env_CloudFrontKeyPairId), "CloudFrontKeyPairId")
-	rootCmd.PersistentFlags().IntVarP(&Limit, "limit", "l", limit, "Restrict to retrieving this number of riders' data. 0 means no limit - get them all.")
+	rootCmd.PersistentFlags().StringVarP(&CloudFrontPolicy, "CloudFrontPolicy", "a", os.Getenv(env_CloudFrontPolicy), "CloudFrontPolicy")
+	rootCmd.PersistentFlags().StringVarP(&CloudFrontSignature, "CloudFrontSignature", "b", os.Getenv(env_CloudFrontSignature), "CloudFrontSignature")
+	rootCmd.PersistentFlags().StringVarP(&CloudFrontKeyPairId, "CloudFrontKeyPairId", "c", os.Getenv(env_CloudFrontKeyPairId), "CloudFrontKeyPairId")
+	rootCmd.PersistentFlags().StringVar(&BaseURL, "baseurl", os.Getenv(env_BaseURL), "ZwiftPower base URL, e.g. to use a mirror. Defaults to "+zp.DefaultBaseURL)
+	rootCmd.PersistentFlags().IntVarP(&Limit, "limit", "l", envInt(env_Limit, 0), "Restrict to retrieving this number of riders' data. 0 means no limit - get them all.")
+	rootCmd.PersistentFlags().IntVar(&Concurrency, "concurrency", envInt(env_Concurrency, zp.DefaultConcurrency), "Number of riders' data to retrieve in parallel")
+	rootCmd.PersistentFlags().Float64Var(&RateLimit, "ratelimit", envFloat(env_RateLimit, zp.DefaultRateLimit), "Maximum average requests per second to ZwiftPower. 0 means no limit.")
+	rootCmd.PersistentFlags().IntVar(&Retries, "retries", envInt(env_Retries, zp.DefaultRetries), "Number of times to retry a request that is throttled or gets a server error")
+	rootCmd.PersistentFlags().IntVar(&MaxFailures, "maxfailures", envInt(env_MaxFailures, 0), "Number of riders whose data can't be imported before the run counts as failed")
+	rootCmd.PersistentFlags().IntSliceVar(&Windows, "windows", envInts(env_Windows, zp.DefaultWindows), "Rolling windows, in days, to report each rider's race power over, e.g. 7,14,180,365")
+	rootCmd.PersistentFlags().StringSliceVar(&Periods, "periods", envStrings(env_Periods), "Periods to report race power over instead of the windows: a number of days, month, ytd, or name:YYYY-MM-DD:YYYY-MM-DD for a season")
+	rootCmd.PersistentFlags().DurationSliceVar(&Durations, "durations", envDurations(env_Durations, defaultDurations()), "Durations to report each rider's best power for, e.g. 20m,10m,10s")
+	rootCmd.PersistentFlags().StringSliceVar(&EventSets, "event-sets", envStrings(env_EventSets), "Kinds of event to report power for, each with its own columns: races, or classes joined with +, from race, tt, ride, workout and other. Defaults to races.")
+	rootCmd.PersistentFlags().StringSliceVar(&TTKeywords, "tt-keywords", envStrings(env_TTKeywords), "Words in a race's title that make it a TT. Defaults to TT, TTT, Time Trial and Chrono.")
+	rootCmd.PersistentFlags().StringVar(&Sources, "sources", os.Getenv(env_Sources), "Show which event each best value came from: none, columns (an extra column naming the event) or links (link each value to its event in Google Sheets)")
+	rootCmd.PersistentFlags().StringSliceVar(&Stats, "stats", envStrings(env_Stats), "Statistics to report alongside the best values in each power group: mean, median, or e.g. 3rd for the third best")
+	rootCmd.PersistentFlags().StringVar(&CPModel, "cp", os.Getenv(env_CPModel), "Fit a 2 or 3 parameter critical power model to each power group and report CP, W' and CP in W/kg")
+	rootCmd.PersistentFlags().IntVar(&TrendWeeks, "trend-weeks", envInt(env_TrendWeeks, 0), "Report whether each rider's form is going up, flat or down over this many weeks. 0 means no trend columns.")
+	rootCmd.PersistentFlags().BoolVar(&Categories, "categories", envBool(env_Categories, false), "Work out each rider's category from their last 90 days of races, and report how close they are to the next category up")
+	rootCmd.PersistentFlags().Float64Var(&UpgradeMargin, "upgrade-margin", envFloat(env_UpgradeMargin, zp.DefaultCategoryEngine.Margin), "Riders within this many W/kg of the next category up are at risk of an upgrade")
+	rootCmd.PersistentFlags().BoolVar(&Scores, "scores", envBool(env_Scores, false), "Report each rider's Zwift racing score and the category it puts them in")
+	rootCmd.PersistentFlags().StringVar(&ScoreBands, "score-bands", os.Getenv(env_ScoreBands), "The lowest racing score in each category, as category:score. Defaults to "+zp.DefaultScoreBands.String())
+	rootCmd.PersistentFlags().StringSliceVar(&ScoreCategories, "score-categories", envStrings(env_ScoreCategories), "Only export riders whose racing score puts them in one of these categories, e.g. B,C")
+	rootCmd.PersistentFlags().BoolVar(&Weights, "weights", envBool(env_Weights, false), "Report the range of each rider's weight in their events, and races where they were suddenly lighter")
+	rootCmd.PersistentFlags().StringVar(&WeightBasis, "weight-basis", os.Getenv(env_WeightBasis), "Work out W/kg from watts and this weight: reported (ZwiftPower's W/kg), current, event or median")
+	rootCmd.PersistentFlags().Float64Var(&WeightDrop, "weight-drop", envFloat(env_WeightDrop, zp.DefaultWeightCheck.Drop), "Flag races where the rider was at least this many kg lighter than in the two weeks before")
+	rootCmd.PersistentFlags().StringVar(&SnapshotFile, "snapshot", os.Getenv(env_Snapshot), "File to keep a snapshot of the team in, so that each run can report what changed since the last one. Defaults to snapshot.json in the storage bucket when running as a service.")
+	rootCmd.PersistentFlags().StringVar(&ChangesFile, "changes-file", os.Getenv(env_ChangesFile), "File to write the changes to the team since the last run to")
+	rootCmd.PersistentFlags().StringVar(&ChangesSheet, "changes-sheet", envString(env_ChangesSheet, "Changes"), "Google sheets sheet name for the changes to the team since the last run")
+	rootCmd.PersistentFlags().Float64Var(&WeightChange, "weight-change", envFloat(env_WeightChange, zp.DefaultRosterCheck.Weight), "Report riders whose weight has changed by at least this many kg since the last run")
+	rootCmd.PersistentFlags().DurationVar(&Timeout, "timeout", envDuration(env_Timeout, 0), "Give up on the import after this long, e.g. 10m. 0 means no time limit.")
+	rootCmd.PersistentFlags().StringVar(&CacheDir, "cache-dir", os.Getenv(env_CacheDir), "Directory for cached ZwiftPower responses. Defaults to the user cache directory, or the storage bucket when running as a service.")
+	rootCmd.PersistentFlags().DurationVar(&CacheTTL, "cache-ttl", envDuration(env_CacheTTL, 6*time.Hour), "Use cached ZwiftPower responses younger than this without checking for changes")
+	rootCmd.PersistentFlags().BoolVar(&NoCache, "no-cache", false, "Don't cache ZwiftPower responses")
+	rootCmd.PersistentFlags().BoolVar(&Refresh, "refresh", false, "Ignore cached ZwiftPower responses and fetch everything again")
 	rootCmd.AddCommand(localCmd)
 	rootCmd.AddCommand(riderCmd)
+	rootCmd.AddCommand(historyCmd)
 	rootCmd.Execute()
 }
 
-func setOutput(filename string) (io.WriteCloser, error) {
-	ctx := context.Background()
+func newZPClient() (*zp.Client, error) {
+	opts := []zp.Option{
+		zp.WithCookies(zp.CloudFrontCookies(CloudFrontPolicy, CloudFrontSignature, CloudFrontKeyPairId)...),
+		zp.WithConcurrency(Concurrency),
+		zp.WithRateLimit(RateLimit, zp.DefaultBurst),
+		zp.WithRetries(Retries, zp.DefaultRetryDelay),
+		zp.WithDurations(zp.Durations(Durations...)...),
+	}
+
+	periods, err := reportPeriods()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, zp.WithPeriods(periods...))
+
+	sets, err := reportEventSets()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, zp.WithEventSets(sets...))
+
+	if len(TTKeywords) > 0 {
+		opts = append(opts, zp.WithClassifier(zp.NewClassifier(TTKeywords...)))
+	}
+
+	if _, err := zp.ParseSourceStyle(Sources); err != nil {
+		return nil, err
+	}
+
+	if _, err := reportStats(); err != nil {
+		return nil, err
+	}
+
+	if _, err := zp.ParseCPModel(CPModel); err != nil {
+		return nil, err
+	}
+
+	if TrendWeeks > 0 {
+		opts = append(opts, zp.WithTrend(zp.TrendAnalyser{Weeks: TrendWeeks, Flat: zp.DefaultTrendAnalyser.Flat}))
+	}
+
+	if _, err := reportScoreBands(); err != nil {
+		return nil, err
+	}
+
+	basis, err := zp.ParseWeightBasis(WeightBasis)
+	if err != nil {
+		return nil, err
+	}
+	check := zp.DefaultWeightCheck
+	check.Drop = WeightDrop
+	opts = append(opts, zp.WithWeightBasis(basis), zp.WithWeightCheck(check))
+
+	engine := zp.DefaultCategoryEngine
+	engine.Margin = UpgradeMargin
+	opts = append(opts, zp.WithCategoryEngine(engine))
+
+	if AsOf != "" {
+		asOf, err := asOfTime()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, zp.WithReferenceTime(asOf))
+	}
+
+	if BaseURL != "" {
+		opts = append(opts, zp.WithBaseURL(BaseURL))
+	}
+
+	if !NoCache {
+		opts = append(opts, zp.WithCache(responseCache(), CacheTTL))
+		if Refresh {
+			opts = append(opts, zp.WithRefresh())
+		}
+	}
+
+	return zp.NewClient(opts...)
+}
+
+// asOfTime is the time we're reporting as of: the end of the --as-of date, or now
+func asOfTime() (time.Time, error) {
+	asOf, err := parseDate(AsOf)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("can't parse --as-of: %v", err)
+	}
+	if asOf.IsZero() {
+		return time.Now(), nil
+	}
+	// Include the whole of the day
+	return asOf.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// reportPeriods are the periods we report race power over: the ones given with --periods,
+// or else the rolling windows
+func reportPeriods() ([]zp.Period, error) {
+	if len(Periods) == 0 {
+		return zp.Windows(Windows...), nil
+	}
+
+	output := make([]zp.Period, len(Periods))
+	for i, s := range Periods {
+		p, err := zp.ParsePeriod(s)
+		if err != nil {
+			return nil, err
+		}
+		output[i] = p
+	}
+	return output, nil
+}
+
+// reportEventSets are the kinds of event we report power for, from --event-sets
+func reportEventSets() ([]zp.EventSet, error) {
+	if len(EventSets) == 0 {
+		return []zp.EventSet{zp.Races}, nil
+	}
+
+	output := make([]zp.EventSet, len(EventSets))
+	for i, s := range EventSets {
+		set, err := zp.ParseEventSet(s)
+		if err != nil {
+			return nil, err
+		}
+		output[i] = set
+	}
+	return output, nil
+}
+
+// reportStats are the statistics we report, from --stats
+func reportStats() ([]zp.Stat, error) {
+	output := make([]zp.Stat, len(Stats))
+	for i, s := range Stats {
+		stat, err := zp.ParseStat(s)
+		if err != nil {
+			return nil, err
+		}
+		output[i] = stat
+	}
+	return output, nil
+}
+
+// reportScoreBands are the racing score categories, from --score-bands
+func reportScoreBands() (zp.ScoreBands, error) {
+	if ScoreBands == "" {
+		return zp.DefaultScoreBands, nil
+	}
+	return zp.ParseScoreBands(ScoreBands)
+}
+
+// layout is the team spreadsheet's columns, following what we import
+func layout() zp.Layout {
+	// newZPClient has already checked all of these
+	periods, _ := reportPeriods()
+	sets, _ := reportEventSets()
+	sources, _ := zp.ParseSourceStyle(Sources)
+	stats, _ := reportStats()
+	cp, _ := zp.ParseCPModel(CPModel)
+	var scores zp.ScoreBands
+	if Scores {
+		scores, _ = reportScoreBands()
+	}
+	return zp.Layout{
+		EventSets: sets,
+		Periods:   periods,
+		Durations: zp.Durations(Durations...),
+		Sources:   sources,
+		Stats:     stats,
+		CP:        cp,
+		Trend:     TrendWeeks > 0,
+		Category:  Categories,
+		Scores:    scores,
+		Weights:   Weights,
+	}
+}
+
+// commandContext is cancelled if the command is interrupted or runs for longer than Timeout
+func commandContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if Timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeout(ctx, Timeout)
+		cancelAll := cancel
+		cancel = func() {
+			stop()
+			cancelAll()
+		}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigs:
+			log.Printf("Received %v, stopping", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+	}()
+
+	return ctx, cancel
+}
+
+// responseCache is where ZwiftPower responses are cached: the storage bucket when running
+// as a service, otherwise a local directory
+func responseCache() zp.Cache {
+	if storageClient != nil && CacheDir == "" {
+		return gcsCache{bkt: storageClient.Bucket(bucketName), prefix: "cache/"}
+	}
+
+	dir := CacheDir
+	if dir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			userCacheDir = os.TempDir()
+		}
+		dir = filepath.Join(userCacheDir, "zwiftpower")
+	}
+	return zp.DirCache(dir)
+}
+
+// output is where a report goes: a sheet in the spreadsheet if there is one, otherwise an
+// object in the storage bucket when running as a service, otherwise a file, or stdout if
+// there isn't a file name
+type output struct {
+	sheet    string
+	object   string
+	filename string
+}
+
+// resultsOutput is where the team's data goes
+func resultsOutput() output {
+	return output{sheet: SpreadsheetSheet, object: "results.csv", filename: Filename}
+}
+
+// historyOutput is where a rider's history goes, so that it doesn't replace the team's data
+func historyOutput() output {
+	return output{sheet: HistorySheet, object: "history.csv", filename: HistoryFile}
+}
+
+// changesOutput is where the changes to the team since the last run go
+func changesOutput() output {
+	return output{sheet: ChangesSheet, object: "changes.csv", filename: ChangesFile}
+}
+
+func setOutput(ctx context.Context, out output) (io.WriteCloser, error) {
+	filename := out.filename
 
 	if SpreadsheetID != "" {
 		log.Printf("Writing to spreadsheet")
-		sw, err := NewSpreadsheetWriter(ctx, SpreadsheetID, SpreadsheetSheet)
+		updated, err := asOfTime()
+		if err != nil {
+			return nil, err
+		}
+		sw, err := NewSpreadsheetWriter(ctx, SpreadsheetID, out.sheet, updated)
 		if err != nil {
 			return nil, fmt.Errorf("error getting spreadsheet client: %v", err)
 		}
@@ -144,7 +706,7 @@ func setOutput(filename string) (io.WriteCloser, error) {
 	// Upload an object with storage.Writer.
 	if storageClient != nil {
 		log.Printf("Writing to storage bucket")
-		bkt := storageClient.Bucket("revo-rider-aardvark")
+		bkt := storageClient.Bucket(bucketName)
 		attrs, err := bkt.Attrs(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("error getting bucket attributes: %v", err)
@@ -152,7 +714,7 @@ func setOutput(filename string) (io.WriteCloser, error) {
 
 		log.Printf("bucket %s, created at %s, is located in %s with storage class %s\n",
 			attrs.Name, attrs.Created, attrs.Location, attrs.StorageClass)
-		sc := bkt.Object("results.csv").NewWriter(ctx)
+		sc := bkt.Object(out.object).NewWriter(ctx)
 		return sc, nil
 	}
 
@@ -170,53 +732,205 @@ func setOutput(filename string) (io.WriteCloser, error) {
 	return f, err
 }
 
-func ImportTeam(clubID int, limit int) error {
-	riders, err := zp.ImportTeam(clubID, limit)
+// ImportTeam writes the team's data to the selected output. If some riders couldn't be
+// imported, the rest are written and the failures are returned, unless none of the riders
+// were imported, in which case nothing is written. If we're keeping snapshots, the changes
+// to the team since the last run are written and returned too.
+func ImportTeam(ctx context.Context, clubID int, limit int) ([]zp.RosterChange, *zp.ImportError, error) {
+	client, err := newZPClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting client: %w", err)
+	}
+
+	var failures *zp.ImportError
+	riders, err := client.ImportTeamContext(ctx, clubID, limit)
+	if err != nil && !errors.As(err, &failures) {
+		return nil, nil, fmt.Errorf("error in ImportTeam: %w", err)
+	}
+
+	// Don't replace the last good results with an empty team
+	if failures != nil && len(riders) == 0 {
+		log.Printf("Only imported %d of %d riders, so leaving the output as it was", len(riders), failures.Riders)
+		return nil, failures, nil
+	}
+
+	// Compare the whole team with last time, before any filtering
+	var snapshot zp.Snapshot
+	var changes []zp.RosterChange
+	if snapshotting() {
+		snapshot, changes, err = compareWithSnapshot(ctx, riders, failures)
+		if err != nil {
+			return nil, failures, fmt.Errorf("checking for changes to the team: %v", err)
+		}
+	}
+
+	if len(ScoreCategories) > 0 {
+		bands, _ := reportScoreBands()
+		riders = bands.Filter(riders, ScoreCategories...)
+	}
+
+	layout := layout()
+	layout.BaseURL = client.BaseURL()
+	rows := make([][]string, len(riders))
+	for i, riderDetail := range riders {
+		rows[i] = layout.Row(riderDetail)
+	}
+
+	if err := writeRows(ctx, resultsOutput(), layout.Headers(), rows); err != nil {
+		return nil, failures, err
+	}
+
+	if !snapshotting() {
+		return nil, failures, nil
+	}
+
+	if SpreadsheetID != "" || storageClient != nil || ChangesFile != "" {
+		rows := make([][]string, len(changes))
+		for i, c := range changes {
+			rows[i] = c.Strings()
+		}
+		if err := writeRows(ctx, changesOutput(), zp.RosterChangeHeaders(), rows); err != nil {
+			return nil, failures, err
+		}
+	}
+
+	// Only move the snapshot on once the changes have been written, so that they aren't lost
+	if err := saveSnapshot(ctx, snapshot); err != nil {
+		return changes, failures, fmt.Errorf("saving snapshot: %v", err)
+	}
+	return changes, failures, nil
+}
+
+// compareWithSnapshot compares the riders with the snapshot from the last run, and
+// returns a new snapshot along with what changed
+func compareWithSnapshot(ctx context.Context, riders []zp.RiderDetail, failures *zp.ImportError) (zp.Snapshot, []zp.RosterChange, error) {
+	previous, err := loadSnapshot(ctx)
 	if err != nil {
-		return fmt.Errorf("error in ImportTeam: %v", err)
+		return zp.Snapshot{}, nil, err
 	}
 
-	f, err := setOutput(Filename)
+	var missing []int
+	if failures != nil {
+		for _, f := range failures.Failures {
+			missing = append(missing, f.Zwid)
+		}
+	}
+
+	taken, err := asOfTime()
 	if err != nil {
-		return fmt.Errorf("opening file %s: %v", Filename, err)
+		return zp.Snapshot{}, nil, err
+	}
+	current, changes := zp.RosterCheck{Weight: WeightChange}.Update(previous, riders, missing, taken)
+	log.Printf("%d changes to the team since %s", len(changes), previous.Taken.Format(time.RFC3339))
+	return current, changes, nil
+}
+
+// reportChanges lists the changes to the team since the last run
+func reportChanges(w io.Writer, changes []zp.RosterChange) {
+	fmt.Fprintf(w, "%d changes to the team since the last run:\n", len(changes))
+	for _, c := range changes {
+		fmt.Fprintf(w, "  %s\n", strings.Join(c.Strings(), ","))
 	}
+}
+
+// writeRows writes headers followed by rows to out
+func writeRows(ctx context.Context, out output, headers []string, rows [][]string) error {
+	f, err := setOutput(ctx, out)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %v", out.filename, err)
+	}
+	closed := false
 	defer func() {
-		err := f.Close()
-		if err != nil {
-			log.Printf("closing: %v", err)
+		if !closed {
+			f.Close()
 		}
 	}()
 
 	writer := NewRowWriter(f)
-	defer func() {
-		log.Printf("About to flush")
-		writer.Flush()
-	}()
 
 	// headers
-	err = writer.WriteRow(zp.ColumnHeaders())
+	err = writer.WriteRow(headers)
 	if err != nil {
 		return fmt.Errorf("writing to file: %v", err)
 	}
 
-	for i, riderDetail := range riders {
-		var err error
-
-		// fmt.Printf("%v\n", riders[i])
-		err = writer.WriteRow(riderDetail.Strings())
+	for _, row := range rows {
+		err = writer.WriteRow(row)
 		if err != nil {
 			return fmt.Errorf("writing to file: %v", err)
 		}
+	}
 
-		if limit > 0 && i >= (limit-1) {
-			log.Printf("Limiting output to %d riders", limit)
-			break
-		}
+	log.Printf("About to flush")
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("writing to file: %v", err)
 	}
 
+	closed = true
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing: %v", err)
+	}
 	return nil
 }
 
+// RiderHistory writes every event for the rider that passes the filters to the history output
+func RiderHistory(ctx context.Context, riderID int, filters ...zp.EventFilter) error {
+	client, err := newZPClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := client.RiderHistory(ctx, riderID)
+	if err != nil {
+		return err
+	}
+
+	events = zp.FilterEvents(events, filters...)
+	rows := make([][]string, len(events))
+	for i, e := range events {
+		rows[i] = e.Strings(client.Durations())
+	}
+
+	return writeRows(ctx, historyOutput(), zp.EventColumnHeaders(client.Durations()), rows)
+}
+
+// problem explains an error to whoever is running the import
+type problem struct {
+	message    string
+	exitCode   int
+	httpStatus int
+}
+
+// problems are checked in order, so the first one that matches an error is used
+var problems = []struct {
+	err error
+	problem
+}{
+	{zp.ErrAuthExpired, problem{"ZwiftPower rejected our credentials. The CloudFront cookies have probably expired and need to be refreshed.", 2, http.StatusBadGateway}},
+	{zp.ErrRateLimited, problem{"ZwiftPower is throttling our requests. Try again later, or with a lower rate limit.", 3, http.StatusTooManyRequests}},
+	{zp.ErrNotFound, problem{"ZwiftPower doesn't know that team or rider.", 4, http.StatusNotFound}},
+	{zp.ErrPrivateProfile, problem{"The rider's ZwiftPower profile is private.", 5, http.StatusForbidden}},
+	{zp.ErrMalformed, problem{"ZwiftPower sent data we couldn't understand.", 6, http.StatusInternalServerError}},
+	{context.DeadlineExceeded, problem{"The import took too long.", 7, http.StatusGatewayTimeout}},
+}
+
+func describeError(err error) problem {
+	for _, p := range problems {
+		if errors.Is(err, p.err) {
+			return p.problem
+		}
+	}
+	return problem{"Something went wrong.", 1, http.StatusInternalServerError}
+}
+
+// reportFailures lists the riders that couldn't be imported
+func reportFailures(w io.Writer, failures *zp.ImportError) {
+	fmt.Fprintf(w, "%d of %d riders could not be imported:\n", len(failures.Failures), failures.Riders)
+	for _, f := range failures.Failures {
+		fmt.Fprintf(w, "  %s (%d): %s %v\n", f.Name, f.Zwid, describeError(f).message, f.Err)
+	}
+}
+
 func HelloZP(w http.ResponseWriter, r *http.Request) {
 	clubID, err := strconv.Atoi(os.Getenv(env_ClubID))
 
@@ -224,12 +938,32 @@ func HelloZP(w http.ResponseWriter, r *http.Request) {
 		log.Fatalf("Environment variable %v must be provided.", env_ClubID)
 	}
 
-	err = ImportTeam(clubID, Limit)
+	ctx := r.Context()
+	if Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, Timeout)
+		defer cancel()
+	}
+
+	changes, failures, err := ImportTeam(ctx, clubID, Limit)
 
 	if err != nil {
+		problem := describeError(err)
 		fmt.Fprintf(os.Stderr, "Error getting ZwiftPower data for %d: %v", clubID, err)
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("%v", err)))
+		w.WriteHeader(problem.httpStatus)
+		fmt.Fprintf(w, "%s\n%v\n", problem.message, err)
+	}
+
+	if failures != nil {
+		reportFailures(os.Stderr, failures)
+		if err == nil && len(failures.Failures) > MaxFailures {
+			w.WriteHeader(describeError(failures).httpStatus)
+		}
+		reportFailures(w, failures)
+	}
+
+	if err == nil && snapshotting() {
+		reportChanges(w, changes)
 	}
 
 	fmt.Fprintf(w, "Reading data for %d\n", clubID)
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportTeamAuthExpired(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	BaseURL, NoCache, Retries, Filename = srv.URL, true, 0, os.DevNull
+	defer func() { BaseURL, NoCache, Retries, Filename = "", false, 0, "" }()
+
+	_, _, err := ImportTeam(context.Background(), 1234, 0)
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	problem := describeError(err)
+	if problem.exitCode != 2 || problem.httpStatus != http.StatusBadGateway {
+		t.Errorf("got exit code %d and status %d for %v, expected 2 and 502", problem.exitCode, problem.httpStatus, err)
+	}
+
+	os.Setenv(env_ClubID, "1234")
+	defer os.Unsetenv(env_ClubID)
+	rec := httptest.NewRecorder()
+	HelloZP(rec, httptest.NewRequest(http.MethodGet, "/trigger", nil))
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "credentials") {
+		t.Errorf("got status %d and body %q, expected 502", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPartialRunsLeaveSnapshotAlone(t *testing.T) {
+	SnapshotFile = "snapshot.json"
+	defer func() { SnapshotFile, AsOf = "", "" }()
+
+	if !snapshotting() {
+		t.Errorf("expected a snapshot file to turn on snapshots")
+	}
+	AsOf = "2021-03-31"
+	if snapshotting() {
+		t.Errorf("expected --as-of to turn off snapshots")
+	}
+	AsOf, Limit = "", 5
+	defer func() { Limit = 0 }()
+	if snapshotting() {
+		t.Errorf("expected --limit to turn off snapshots")
+	}
+}
+
+func TestImportTeamWritesOutputWhenRidersFail(t *testing.T) {
+	roster := `{"data":[{"name":"Liz Rice","zwid":98588,"w":["59.0",1],"div":20,"divw":0},{"name":"Private Rider","zwid":1111,"w":["70.0",1],"div":30,"divw":0}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/teams/"):
+			w.Write([]byte(roster))
+		case strings.Contains(r.URL.Path, "/98588_"):
+			w.Write([]byte(`{"data":[]}`))
+		default:
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	Filename = filepath.Join(t.TempDir(), "results.csv")
+	if err := ioutil.WriteFile(Filename, []byte("last good results\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	BaseURL, NoCache, Retries = srv.URL, true, 0
+	defer func() { BaseURL, NoCache, Retries, Filename = "", false, 0, "" }()
+
+	// One rider failing is more than MaxFailures, but the rest of the team is still written
+	_, failures, err := ImportTeam(context.Background(), 1234, 0)
+	if err != nil || failures == nil || len(failures.Failures) != 1 {
+		t.Fatalf("got failures %v and error %v, expected one failure", failures, err)
+	}
+	if describeError(failures).exitCode == 0 {
+		t.Errorf("expected the failure to count against the run")
+	}
+	data, err := ioutil.ReadFile(Filename)
+	if err != nil || !strings.Contains(string(data), "Liz Rice") || strings.Contains(string(data), "Private Rider") {
+		t.Errorf("got %q, %v, expected just the riders that were imported", data, err)
+	}
+
+	// If nobody could be imported, the last results are left alone
+	if err := ioutil.WriteFile(Filename, []byte("last good results\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	roster = `{"data":[{"name":"Private Rider","zwid":1111,"w":["70.0",1],"div":30,"divw":0}]}`
+	_, failures, err = ImportTeam(context.Background(), 1234, 0)
+	if err != nil || failures == nil || len(failures.Failures) != 1 {
+		t.Fatalf("got failures %v and error %v, expected one failure", failures, err)
+	}
+	data, err = ioutil.ReadFile(Filename)
+	if err != nil || string(data) != "last good results\n" {
+		t.Errorf("got %q, %v, expected the output to be left alone", data, err)
+	}
+}
+
+func TestRiderHistoryLeavesTeamOutputAlone(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	Filename, HistoryFile = filepath.Join(dir, "results.csv"), filepath.Join(dir, "history.csv")
+	if err := ioutil.WriteFile(Filename, []byte("team results\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	BaseURL, NoCache, Retries = srv.URL, true, 0
+	defer func() { BaseURL, NoCache, Retries, Filename, HistoryFile = "", false, 0, "", "" }()
+
+	if err := RiderHistory(context.Background(), 98588); err != nil {
+		t.Fatalf("RiderHistory: %v", err)
+	}
+	data, err := ioutil.ReadFile(Filename)
+	if err != nil || string(data) != "team results\n" {
+		t.Errorf("got %q, %v, expected the team's data to be left alone", data, err)
+	}
+	if data, err := ioutil.ReadFile(HistoryFile); err != nil || !strings.HasPrefix(string(data), "Date,") {
+		t.Errorf("got %q, %v, expected the history in its own file", data, err)
+	}
+}
@@ -25,7 +25,8 @@ type spreadsheetWriter struct {
 	sheet        string // Sheet is the name of the sheet we're writing to
 }
 
-func NewSpreadsheetWriter(ctx context.Context, spreadsheetID string, spreadsheetSheet string) (*spreadsheetWriter, error) {
+// NewSpreadsheetWriter clears the sheet ready to write to it, adding it if the spreadsheet doesn't have it yet, and notes that the data is as of updated
+func NewSpreadsheetWriter(ctx context.Context, spreadsheetID string, spreadsheetSheet string, updated time.Time) (*spreadsheetWriter, error) {
 	log.Printf("Getting new spreadsheetWriter")
 	srv, err := sheets.NewService(ctx)
 	if err != nil {
@@ -44,32 +45,45 @@ func NewSpreadsheetWriter(ctx context.Context, spreadsheetID string, spreadsheet
 		srv:          srv,
 	}
 
-	// Clear the current contents
-	// clearRequest := sheets.ClearValuesRequest{
-	// 	Ranges: []string{
-	// 		fmt.Sprintf("%s!*", sw.sheet),
-	// 	},
-	// }
-	_, err = srv.Spreadsheets.Values.Clear(sw.id, fmt.Sprintf("%s!A1:ZZ", sw.sheet), &sheets.ClearValuesRequest{}).Do()
-	if err != nil {
-		log.Printf("clearing spreadsheet values: %v", err)
-	}
-
-	// Get the sheet ID
-	var sheetID int64
+	// Get the sheet ID, adding the sheet if it isn't there yet
 	resp, err := sw.srv.Spreadsheets.Get(sw.id).Do()
 	if err != nil {
-		log.Printf("getting spreadsheet data: %v", err)
+		return nil, fmt.Errorf("getting spreadsheet data: %v", err)
 	}
 
+	var sheetID int64
+	found := false
 	for _, s := range resp.Sheets {
 		log.Printf("Sheet name %s has id %d", s.Properties.Title, s.Properties.SheetId)
 		if s.Properties.Title == sw.sheet {
 			sheetID = s.Properties.SheetId
+			found = true
+		}
+	}
+
+	if !found {
+		log.Printf("Adding sheet %s", sw.sheet)
+		addSheet := &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{{
+				AddSheet: &sheets.AddSheetRequest{
+					Properties: &sheets.SheetProperties{Title: sw.sheet},
+				},
+			}},
 		}
+		added, err := srv.Spreadsheets.BatchUpdate(sw.id, addSheet).Do()
+		if err != nil {
+			return nil, fmt.Errorf("adding sheet %s: %v", sw.sheet, err)
+		}
+		sheetID = added.Replies[0].AddSheet.Properties.SheetId
+	}
+
+	// Clear the current contents
+	_, err = srv.Spreadsheets.Values.Clear(sw.id, fmt.Sprintf("%s!A1:ZZ", sw.sheet), &sheets.ClearValuesRequest{}).Do()
+	if err != nil {
+		return nil, fmt.Errorf("clearing spreadsheet values: %v", err)
 	}
 
-	// Add a note in cell A1 of this sheet with the current date
+	// Add a note in cell A1 of this sheet with the date of the data
 	updateCellsRequest := &sheets.UpdateCellsRequest{
 		Range: &sheets.GridRange{
 			SheetId:          sheetID,
@@ -81,7 +95,7 @@ func NewSpreadsheetWriter(ctx context.Context, spreadsheetID string, spreadsheet
 		Fields: "*",
 		Rows: []*sheets.RowData{{
 			Values: []*sheets.CellData{{
-				Note: fmt.Sprintf("Last updated: %s", time.Now().Format("2006-January-02")),
+				Note: fmt.Sprintf("Last updated: %s", updated.Format("2006-January-02")),
 			}},
 		}},
 	}
@@ -114,13 +128,13 @@ func (sw *spreadsheetWriter) WriteRow(record []string) error {
 
 	if len(sw.values) >= sw.batch_length {
 		log.Printf("Flush this data")
-		sw.Flush()
+		return sw.Flush()
 	}
 
 	return nil
 }
 
-func (sw *spreadsheetWriter) Flush() {
+func (sw *spreadsheetWriter) Flush() error {
 	// Start at row 2 to leave the header row intact
 	rangeData := fmt.Sprintf("%s!%s%d:%s%d", sw.sheet, sw.min_cols, sw.min_rows, sw.max_cols, sw.max_rows)
 	log.Printf("Writing data to spreadsheet range %s, length %d", rangeData, len(sw.values))
@@ -142,23 +156,27 @@ func (sw *spreadsheetWriter) Flush() {
 	})
 	_, err := sw.srv.Spreadsheets.Values.BatchUpdate(sw.id, rb).Do()
 	if err != nil {
-		log.Printf("writing to spreadsheet: %v", err)
+		return fmt.Errorf("writing to spreadsheet: %v", err)
 	}
 
 	// Update where we will write to next time, and reset the values
 	sw.min_rows = sw.max_rows
 	sw.max_rows = sw.min_rows
 	sw.values = nil
-	return
+	return nil
 }
 
+// Close writes any rows that haven't been written yet
 func (sw *spreadsheetWriter) Close() error {
-	return nil
+	if len(sw.values) == 0 {
+		return nil
+	}
+	return sw.Flush()
 }
 
 type rowWriter interface {
 	WriteRow(record []string) error
-	Flush()
+	Flush() error
 }
 
 func NewRowWriter(w io.Writer) rowWriter {
@@ -184,6 +202,11 @@ func (m *myCSV) WriteRow(record []string) error {
 	return m.Writer.Write(record)
 }
 
+func (m *myCSV) Flush() error {
+	m.Writer.Flush()
+	return m.Writer.Error()
+}
+
 // func (m *myCSV) Flush() {
 
 // }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+
+	"cloud.google.com/go/storage"
+	"github.com/lizrice/zwiftpower/zp"
+)
+
+// snapshotObject is where the team snapshot is kept in the storage bucket
+const snapshotObject = "snapshot.json"
+
+// snapshotting is whether we keep a snapshot of the team between runs: in the storage
+// bucket when running as a service, or in the --snapshot file. Reports --as-of an earlier
+// date don't touch the snapshot, because it has to stay the latest one, and nor do runs
+// with a --limit, because the riders past the limit would look like they'd left.
+func snapshotting() bool {
+	return AsOf == "" && Limit == 0 && (SnapshotFile != "" || storageClient != nil)
+}
+
+// loadSnapshot reads the snapshot from the last run, or an empty one if there wasn't one
+func loadSnapshot(ctx context.Context) (zp.Snapshot, error) {
+	var snapshot zp.Snapshot
+	var r io.ReadCloser
+	var err error
+	if SnapshotFile != "" {
+		r, err = os.Open(SnapshotFile)
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("No snapshot in %s yet", SnapshotFile)
+			return snapshot, nil
+		}
+	} else {
+		r, err = storageClient.Bucket(bucketName).Object(snapshotObject).NewReader(ctx)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			log.Printf("No snapshot in the storage bucket yet")
+			return snapshot, nil
+		}
+	}
+	if err != nil {
+		return snapshot, err
+	}
+	defer r.Close()
+
+	err = json.NewDecoder(r).Decode(&snapshot)
+	return snapshot, err
+}
+
+// saveSnapshot keeps the snapshot for the next run
+func saveSnapshot(ctx context.Context, snapshot zp.Snapshot) error {
+	var w io.WriteCloser
+	if SnapshotFile != "" {
+		f, err := os.Create(SnapshotFile)
+		if err != nil {
+			return err
+		}
+		w = f
+	} else {
+		sw := storageClient.Bucket(bucketName).Object(snapshotObject).NewWriter(ctx)
+		sw.ContentType = "application/json"
+		w = sw
+	}
+
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,83 @@
+package zp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventRef identifies the event that a value came from
+type EventRef struct {
+	ID    ID
+	Title string
+	Date  time.Time
+}
+
+// Ref identifies the event
+func (e Event) Ref() EventRef {
+	return EventRef{ID: e.EventID, Title: e.EventTitle, Date: e.EventDate}
+}
+
+// URL is the event's results page on the ZwiftPower site at baseURL, or at
+// DefaultBaseURL if baseURL is ""
+func (r EventRef) URL(baseURL string) string {
+	return siteURL(baseURL) + fmt.Sprintf(zpEventPath, r.ID)
+}
+
+// siteURL is baseURL without a trailing slash, or DefaultBaseURL if baseURL is ""
+func siteURL(baseURL string) string {
+	if baseURL == "" {
+		return DefaultBaseURL
+	}
+	return strings.TrimRight(baseURL, "/")
+}
+
+// String is the event's title and date, e.g. "Tour of Watopia Stage 4 (2020-03-21)",
+// or "" if there's no event
+func (r EventRef) String() string {
+	if r.Title == "" && r.ID == "" {
+		return ""
+	}
+	if r.Date.IsZero() || r.Date.Unix() == 0 {
+		return r.Title
+	}
+	return fmt.Sprintf("%s (%s)", r.Title, r.Date.Format("2006-01-02"))
+}
+
+// Best is a rider's best value for something, such as their 5 minute power, and the
+// event that it came from
+type Best struct {
+	Value float64
+	Event EventRef
+}
+
+// replaceIfGreater keeps newVal from event e if it beats the current best
+func (b *Best) replaceIfGreater(newVal float64, e Event) {
+	if newVal > b.Value {
+		b.Value = newVal
+		b.Event = e.Ref()
+	}
+}
+
+// SourceStyle is how a Layout shows which event each best value came from
+type SourceStyle int
+
+// These are the ways of showing where best values came from
+const (
+	NoSources     SourceStyle = iota
+	SourceColumns             // An extra column after each value, naming the event
+	SourceLinks               // Each value is a Google Sheets HYPERLINK to the event
+)
+
+// ParseSourceStyle reads "none", "columns" or "links"
+func ParseSourceStyle(s string) (SourceStyle, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return NoSources, nil
+	case "columns":
+		return SourceColumns, nil
+	case "links":
+		return SourceLinks, nil
+	}
+	return NoSources, fmt.Errorf("unknown source style %q: expected none, columns or links", s)
+}
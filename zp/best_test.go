@@ -0,0 +1,82 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBestSources(t *testing.T) {
+	sets := []EventSet{Races, {Name: "Ride", Classes: []EventClass{ClassGroupRide}}}
+	periods := Windows(60)
+	c, _ := newFixtureClient(t,
+		WithEventSets(sets...),
+		WithPeriods(periods...),
+		WithReferenceTime(time.Date(2020, 4, 30, 0, 0, 0, 0, time.UTC)),
+	)
+
+	r, err := c.ImportRider(98588)
+	if err != nil {
+		t.Fatalf("ImportRider: %v", err)
+	}
+
+	races, _ := r.PowerGroup(Races, Rolling(60))
+	best := races.Wpkg[300]
+	if best.Value != 4.0 || best.Event.ID != "555001" || best.Event.Title != "Tour of Watopia Stage 4" || !best.Event.Date.Equal(time.Unix(1584800000, 0)) {
+		t.Errorf("unexpected best 5 minute W/kg in races %+v", best)
+	}
+	if races.FTP.Event.ID != "555001" {
+		t.Errorf("unexpected source for FTP %+v", races.FTP)
+	}
+
+	rides, _ := r.PowerGroup(sets[1], Rolling(60))
+	if rides.Watts[1200].Event.ID != "555002" {
+		t.Errorf("unexpected source for best 20 minute power in rides %+v", rides.Watts[1200])
+	}
+
+	for _, style := range []struct {
+		name   string
+		source SourceStyle
+	}{
+		{"sources_columns", SourceColumns},
+		{"sources_links", SourceLinks},
+	} {
+		l := Layout{EventSets: sets, Periods: periods, Durations: Durations(20 * time.Minute), Sources: style.source}
+		headers, row := l.Headers(), l.Row(r)
+		if len(headers) != len(row) {
+			t.Errorf("%s: %d headers for %d values", style.name, len(headers), len(row))
+		}
+		checkGolden(t, style.name, headers, row)
+	}
+}
+
+func TestLinksUseBaseURL(t *testing.T) {
+	r := EventRef{ID: "555001"}
+	if url := r.URL(""); url != "https://zwiftpower.com/events.php?zid=555001" {
+		t.Errorf("got %q for the default site", url)
+	}
+
+	c, err := NewClient(WithBaseURL("http://mirror.example/"))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if url := r.URL(c.BaseURL()); url != "http://mirror.example/events.php?zid=555001" {
+		t.Errorf("got %q for a mirror", url)
+	}
+
+	// The rider's profile goes to the same site as the links to their events
+	l := Layout{Sources: SourceLinks, BaseURL: c.BaseURL()}
+	if profile := l.Row(RiderDetail{Zwid: 98588})[2]; profile != "http://mirror.example/profile.php?z=98588" {
+		t.Errorf("got profile %q for a mirror", profile)
+	}
+}
+
+func TestParseSourceStyle(t *testing.T) {
+	for in, expected := range map[string]SourceStyle{"": NoSources, "none": NoSources, "Columns": SourceColumns, "links": SourceLinks} {
+		if s, err := ParseSourceStyle(in); err != nil || s != expected {
+			t.Errorf("%q: got %v, %v, expected %v", in, s, err, expected)
+		}
+	}
+	if _, err := ParseSourceStyle("footnotes"); err == nil {
+		t.Errorf("expected an error for an unknown style")
+	}
+}
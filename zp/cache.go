@@ -0,0 +1,81 @@
+package zp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheEntry is a response from ZwiftPower, along with what we need to check whether it has changed
+type CacheEntry struct {
+	Body         []byte
+	ETag         string
+	LastModified string
+	Fetched      time.Time
+}
+
+// Cache stores responses from ZwiftPower, keyed by URL
+type Cache interface {
+	// Get returns the entry for key, or nil if there isn't one
+	Get(ctx context.Context, key string) (*CacheEntry, error)
+	Put(ctx context.Context, key string, entry *CacheEntry) error
+}
+
+// DirCache is a Cache that keeps each entry in its own file in a local directory
+type DirCache string
+
+func (d DirCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(string(d), hex.EncodeToString(sum[:])+".json")
+}
+
+// Get reads the entry for key from the directory
+func (d DirCache) Get(ctx context.Context, key string) (*CacheEntry, error) {
+	data, err := ioutil.ReadFile(d.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Put writes the entry for key to the directory, creating the directory if need be
+func (d DirCache) Put(ctx context.Context, key string, entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and move it into place, so a reader never sees half an entry
+	f, err := ioutil.TempFile(string(d), "tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), d.path(key))
+}
@@ -0,0 +1,163 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// etagServer serves body with an ETag, and answers matching conditional requests with 304
+type etagServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	calls       int
+	notModified int
+}
+
+func newETagServer(t *testing.T, body string) *etagServer {
+	t.Helper()
+	es := &etagServer{}
+	es.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		es.mu.Lock()
+		defer es.mu.Unlock()
+		es.calls++
+
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			es.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(es.Close)
+	return es
+}
+
+func TestDirCache(t *testing.T) {
+	ctx := context.Background()
+	d := DirCache(t.TempDir() + "/cache")
+
+	entry, err := d.Get(ctx, "https://zwiftpower.com/x")
+	if entry != nil || err != nil {
+		t.Fatalf("expected nothing from an empty cache, got %v, %v", entry, err)
+	}
+
+	fetched := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	in := &CacheEntry{Body: []byte(`{"data":[]}`), ETag: `"v1"`, Fetched: fetched}
+	if err := d.Put(ctx, "https://zwiftpower.com/x", in); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	entry, err = d.Get(ctx, "https://zwiftpower.com/x")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(entry.Body) != `{"data":[]}` || entry.ETag != `"v1"` || !entry.Fetched.Equal(fetched) {
+		t.Errorf("got back %+v", entry)
+	}
+}
+
+func TestGetJSONCache(t *testing.T) {
+	cases := []struct {
+		name        string
+		ttl         time.Duration
+		refresh     bool
+		calls       int
+		notModified int
+	}{
+		{name: "fresh", ttl: time.Hour, calls: 1},
+		{name: "stale", ttl: 0, calls: 2, notModified: 1},
+		{name: "refresh", ttl: time.Hour, refresh: true, calls: 2},
+	}
+
+	for _, tc := range cases {
+		es := newETagServer(t, `{"data":[]}`)
+		cache := DirCache(t.TempDir())
+
+		// Fill the cache
+		c, err := NewClient(WithBaseURL(es.URL), WithCache(cache, tc.ttl))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.getJSON(context.Background(), es.URL+"/x", nil); err != nil {
+			t.Fatalf("%s: first getJSON: %v", tc.name, err)
+		}
+
+		opts := []Option{WithBaseURL(es.URL), WithCache(cache, tc.ttl)}
+		if tc.refresh {
+			opts = append(opts, WithRefresh())
+		}
+		c, err = NewClient(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := c.getJSON(context.Background(), es.URL+"/x", nil)
+		if err != nil {
+			t.Fatalf("%s: second getJSON: %v", tc.name, err)
+		}
+		if string(body) != `{"data":[]}` {
+			t.Errorf("%s: got body %q", tc.name, body)
+		}
+		if es.calls != tc.calls || es.notModified != tc.notModified {
+			t.Errorf("%s: server got %d calls with %d not modified, expected %d and %d", tc.name, es.calls, es.notModified, tc.calls, tc.notModified)
+		}
+	}
+}
+
+func TestGetJSONDoesNotCacheMalformed(t *testing.T) {
+	es := newETagServer(t, `<html>Please log in</html>`)
+	cache := DirCache(t.TempDir())
+	c, err := NewClient(WithBaseURL(es.URL), WithCache(cache, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var v club
+		_, err := c.getJSON(context.Background(), es.URL+"/x", &v)
+		if !errors.Is(err, ErrMalformed) {
+			t.Errorf("attempt %d: got %v, expected a malformed response", i, err)
+		}
+	}
+	if es.calls != 2 || es.notModified != 0 {
+		t.Errorf("server got %d calls with %d not modified, expected 2 full responses", es.calls, es.notModified)
+	}
+	if entry, _ := cache.Get(context.Background(), es.URL+"/x"); entry != nil {
+		t.Errorf("expected nothing to be cached, got %q", entry.Body)
+	}
+
+	// Something bad that's already in the cache isn't used either
+	cache.Put(context.Background(), es.URL+"/y", &CacheEntry{Body: []byte(`<html>`), Fetched: time.Now()})
+	var v club
+	if _, err := c.getJSON(context.Background(), es.URL+"/y", &v); !errors.Is(err, ErrMalformed) || es.calls != 3 {
+		t.Errorf("got %v after %d calls, expected ZwiftPower to be asked again", err, es.calls)
+	}
+}
+
+func TestGetJSONIgnoresStaleValues(t *testing.T) {
+	es := newETagServer(t, `{"data":[{"Name":"Ann"}]}`)
+	cache := DirCache(t.TempDir())
+	c, err := NewClient(WithBaseURL(es.URL), WithCache(cache, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An out of date entry with more riders, and a score that ZwiftPower no longer sends
+	stale := `{"data":[{"Name":"Ann","zrs":500},{"Name":"Bob"}]}`
+	cache.Put(context.Background(), es.URL+"/x", &CacheEntry{Body: []byte(stale), Fetched: time.Now().Add(-2 * time.Hour)})
+
+	var v club
+	if _, err := c.getJSON(context.Background(), es.URL+"/x", &v); err != nil {
+		t.Fatalf("getJSON: %v", err)
+	}
+	if len(v.Riders) != 1 || v.Riders[0].RacingScore.Present {
+		t.Errorf("got %+v, expected just what ZwiftPower sent this time", v.Riders)
+	}
+}
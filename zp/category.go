@@ -0,0 +1,132 @@
+package zp
+
+import (
+	"math"
+	"time"
+)
+
+// CategoryBoundary is the least a rider needs to be in a category. They need both the
+// W/kg and the watts.
+type CategoryBoundary struct {
+	Category string
+	Wkg      float64
+	Watts    float64 // An absolute floor, or 0 if there isn't one
+}
+
+// CategoryRules are the boundaries of a set of pace groups, highest category first.
+// Riders who don't reach any of the boundaries are in Lowest.
+type CategoryRules struct {
+	Boundaries []CategoryBoundary
+	Lowest     string
+}
+
+// MensCategories are ZwiftPower's rules for the open categories
+var MensCategories = CategoryRules{
+	Boundaries: []CategoryBoundary{
+		{Category: "A+", Wkg: 5.0},
+		{Category: "A", Wkg: 4.0, Watts: 250},
+		{Category: "B", Wkg: 3.2, Watts: 200},
+		{Category: "C", Wkg: 2.5, Watts: 150},
+	},
+	Lowest: "D",
+}
+
+// WomensCategories are ZwiftPower's rules for the women's categories
+var WomensCategories = CategoryRules{
+	Boundaries: []CategoryBoundary{
+		{Category: "A", Wkg: 3.7},
+		{Category: "B", Wkg: 3.2},
+		{Category: "C", Wkg: 2.5},
+	},
+	Lowest: "D",
+}
+
+// Category is the category for a rider with this W/kg and power, and the boundary of
+// the category above it, or nil if they're already in the top category
+func (r CategoryRules) Category(wkg, watts float64) (string, *CategoryBoundary) {
+	for i, b := range r.Boundaries {
+		if wkg >= b.Wkg && watts >= b.Watts {
+			if i == 0 {
+				return b.Category, nil
+			}
+			return b.Category, &r.Boundaries[i-1]
+		}
+	}
+	if len(r.Boundaries) == 0 {
+		return r.Lowest, nil
+	}
+	return r.Lowest, &r.Boundaries[len(r.Boundaries)-1]
+}
+
+// CategoryStatus is the category a rider's recent races put them in, and how close
+// they are to the next one up
+type CategoryStatus struct {
+	// Category is "" if the rider hasn't raced recently enough to have one
+	Category string
+	Wkg      float64 // The W/kg the category is based on
+	Watts    float64 // The power the category is based on
+
+	Next   string  // The next category up, or "" for riders in the top category
+	Gap    float64 // How many more W/kg the rider needs to reach Next
+	AtRisk bool    // Whether the rider is within the engine's margin of Next
+}
+
+// CategoryEngine works out riders' categories from their race results
+type CategoryEngine struct {
+	Mens     CategoryRules
+	Womens   CategoryRules // For riders in a women's category
+	Days     int           // How many days of races to go by
+	Margin   float64       // Riders within this many W/kg of the next category are at risk of an upgrade
+	Classify Classifier    // Picks out the races that the category goes by
+}
+
+// DefaultCategoryEngine uses ZwiftPower's rules on the last 90 days of races, and
+// counts riders within 0.1 W/kg of the next category as at risk
+var DefaultCategoryEngine = CategoryEngine{
+	Mens:   MensCategories,
+	Womens: WomensCategories,
+	Days:   90,
+	Margin: 0.1,
+}
+
+// Categorise works out the category that a rider's races up to now put them in. Like
+// ZwiftPower, it goes by the higher of their best FTP estimate and 95% of their best
+// 20 minute power, in both W/kg and watts. The gap to the next category allows for its
+// watt floor at the rider's weight, if we know it.
+func (c CategoryEngine) Categorise(events []Event, weight float64, womens bool, now time.Time) CategoryStatus {
+	classify := c.Classify.orDefault()
+
+	var status CategoryStatus
+	window := Rolling(c.Days)
+	raced := false
+	for _, e := range events {
+		if !window.Contains(e.EventDate, now) || !Races.Includes(classify(e)) {
+			continue
+		}
+		raced = true
+		status.Wkg = math.Max(status.Wkg, math.Max(e.WkgFtp.Value, 0.95*e.Wkg20min.Value))
+		status.Watts = math.Max(status.Watts, math.Max(e.WFtp.Value, 0.95*e.W20min.Value))
+	}
+	if !raced {
+		return status
+	}
+
+	rules := c.Mens
+	if womens {
+		rules = c.Womens
+	}
+	category, next := rules.Category(status.Wkg, status.Watts)
+	status.Category = category
+	if next == nil {
+		return status
+	}
+
+	needed := next.Wkg
+	if weight > 0 && next.Watts/weight > needed {
+		needed = next.Watts / weight
+	}
+	status.Next = next.Category
+	status.Gap = math.Max(needed-status.Wkg, 0)
+	status.AtRisk = status.Gap <= c.Margin
+	return status
+}
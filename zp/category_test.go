@@ -0,0 +1,103 @@
+package zp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCategoryRules(t *testing.T) {
+	cases := []struct {
+		rules    CategoryRules
+		wkg      float64
+		watts    float64
+		category string
+		next     string
+	}{
+		{MensCategories, 5.1, 350, "A+", ""},
+		{MensCategories, 4.2, 300, "A", "A+"},
+		{MensCategories, 4.2, 240, "B", "A"}, // Not enough watts for A
+		{MensCategories, 3.2, 200, "B", "A"},
+		{MensCategories, 3.19, 250, "C", "B"},
+		{MensCategories, 2.6, 140, "D", "C"},
+		{MensCategories, 0, 0, "D", "C"},
+		{WomensCategories, 3.8, 200, "A", ""},
+		{WomensCategories, 3.3, 180, "B", "A"},
+		{WomensCategories, 2.4, 150, "D", "C"},
+		{CategoryRules{Lowest: "X"}, 4, 300, "X", ""},
+	}
+	for _, tc := range cases {
+		category, next := tc.rules.Category(tc.wkg, tc.watts)
+		nextCategory := ""
+		if next != nil {
+			nextCategory = next.Category
+		}
+		if category != tc.category || nextCategory != tc.next {
+			t.Errorf("%.2f W/kg and %.0fW: got %q and next %q, expected %q and %q", tc.wkg, tc.watts, category, nextCategory, tc.category, tc.next)
+		}
+	}
+}
+
+func TestCategorise(t *testing.T) {
+	now := time.Date(2021, 3, 12, 18, 0, 0, 0, time.UTC)
+	race := func(daysAgo int, ftp, wkg20 float64, weight float64) Event {
+		return Event{
+			EventType: "TYPE_RACE",
+			EventDate: now.AddDate(0, 0, -daysAgo),
+			WkgFtp:    PowerValue{Value: ftp, Present: true},
+			Wkg20min:  PowerValue{Value: wkg20, Present: true},
+			WFtp:      PowerValue{Value: ftp * weight, Present: true},
+			W20min:    PowerValue{Value: wkg20 * weight, Present: true},
+		}
+	}
+	ride := race(1, 4.5, 4.7, 70)
+	ride.EventType = "TYPE_RIDE"
+
+	cases := []struct {
+		name   string
+		events []Event
+		weight float64
+		womens bool
+		expect CategoryStatus
+	}{
+		{
+			name:   "nothing recent",
+			events: []Event{race(91, 3.5, 3.6, 70)},
+			weight: 70,
+		},
+		{
+			// 95% of 20 minutes is 3.135 W/kg, which beats the FTP estimate
+			name:   "near the top of C",
+			events: []Event{race(10, 3.0, 3.3, 70), race(100, 3.5, 3.6, 70), ride},
+			weight: 70,
+			expect: CategoryStatus{Category: "C", Wkg: 3.135, Watts: 219.45, Next: "B", Gap: 0.065, AtRisk: true},
+		},
+		{
+			name:   "comfortably B",
+			events: []Event{race(30, 3.4, 3.5, 70)},
+			weight: 70,
+			expect: CategoryStatus{Category: "B", Wkg: 3.4, Watts: 238, Next: "A", Gap: 0.6},
+		},
+		{
+			// A light rider needs 250W, which is 4.17 W/kg at 60kg, to be an A
+			name:   "watt floor",
+			events: []Event{race(5, 4.1, 4.2, 60)},
+			weight: 60,
+			expect: CategoryStatus{Category: "B", Wkg: 4.1, Watts: 246, Next: "A", Gap: 250.0/60 - 4.1, AtRisk: true},
+		},
+		{
+			name:   "women's A",
+			events: []Event{race(5, 3.8, 3.9, 55)},
+			weight: 55,
+			womens: true,
+			expect: CategoryStatus{Category: "A", Wkg: 3.8, Watts: 209},
+		},
+	}
+	for _, tc := range cases {
+		got := DefaultCategoryEngine.Categorise(tc.events, tc.weight, tc.womens, now)
+		if got.Category != tc.expect.Category || got.Next != tc.expect.Next || got.AtRisk != tc.expect.AtRisk ||
+			math.Abs(got.Wkg-tc.expect.Wkg) > 1e-9 || math.Abs(got.Watts-tc.expect.Watts) > 1e-9 || math.Abs(got.Gap-tc.expect.Gap) > 1e-9 {
+			t.Errorf("%s: got %+v, expected %+v", tc.name, got, tc.expect)
+		}
+	}
+}
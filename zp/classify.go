@@ -0,0 +1,126 @@
+package zp
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// EventClass is the kind of event a rider took part in
+type EventClass string
+
+// These are the classes of event that a Classifier can give
+const (
+	ClassRace      EventClass = "Race" // A mass-start race
+	ClassTT        EventClass = "TT"   // A time trial
+	ClassGroupRide EventClass = "Ride"
+	ClassWorkout   EventClass = "Workout"
+	ClassOther     EventClass = "Other"
+)
+
+// Classifier decides what class of event e is. Where a Classifier is optional, nil
+// means DefaultClassifier.
+type Classifier func(e Event) EventClass
+
+// orDefault is the classifier, or DefaultClassifier if it's nil
+func (c Classifier) orDefault() Classifier {
+	if c == nil {
+		return DefaultClassifier
+	}
+	return c
+}
+
+// DefaultTTKeywords are the words in a race's title that make DefaultClassifier call it a TT
+var DefaultTTKeywords = []string{"TT", "TTT", "Time Trial", "Chrono"}
+
+// DefaultClassifier classifies events by their ZwiftPower type, using DefaultTTKeywords
+// to pick out TTs among the races
+var DefaultClassifier = NewClassifier(DefaultTTKeywords...)
+
+// NewClassifier returns a Classifier that goes by the ZwiftPower event type. Races whose
+// titles include any of ttKeywords as whole words (ignoring case) are TTs.
+func NewClassifier(ttKeywords ...string) Classifier {
+	keywords := make([]string, len(ttKeywords))
+	for i, k := range ttKeywords {
+		keywords[i] = " " + normaliseTitle(k) + " "
+	}
+
+	return func(e Event) EventClass {
+		switch {
+		case strings.Contains(e.EventType, "TYPE_TT"):
+			return ClassTT
+		case e.IsRace():
+			title := " " + normaliseTitle(e.EventTitle) + " "
+			for _, k := range keywords {
+				if strings.Contains(title, k) {
+					return ClassTT
+				}
+			}
+			return ClassRace
+		case strings.Contains(e.EventType, "WORKOUT"):
+			return ClassWorkout
+		case strings.Contains(e.EventType, "RIDE"), strings.Contains(e.EventType, "GROUP"):
+			return ClassGroupRide
+		default:
+			return ClassOther
+		}
+	}
+}
+
+// normaliseTitle lower-cases s and turns anything that isn't a letter or digit into
+// single spaces, so that keywords can be matched as whole words
+func normaliseTitle(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// EventSet is a set of event classes whose power is collected together
+type EventSet struct {
+	Name    string // Goes in the column headers. Races has no name, so its headers are as they've always been.
+	Classes []EventClass
+}
+
+// Races is all competitive events: mass-start races and TTs
+var Races = EventSet{Classes: []EventClass{ClassRace, ClassTT}}
+
+// Includes reports whether class is in the set
+func (s EventSet) Includes(class EventClass) bool {
+	for _, c := range s.Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseEventSet reads an event set written as "races", or as class names joined with
+// "+", e.g. "tt" or "race+ride"
+func ParseEventSet(s string) (EventSet, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "races") {
+		return Races, nil
+	}
+
+	var set EventSet
+	var names []string
+	for _, name := range strings.Split(s, "+") {
+		class, ok := eventClass(name)
+		if !ok {
+			return EventSet{}, fmt.Errorf("event set %q: unknown class %q", s, name)
+		}
+		set.Classes = append(set.Classes, class)
+		names = append(names, string(class))
+	}
+	set.Name = strings.Join(names, "")
+	return set, nil
+}
+
+func eventClass(name string) (EventClass, bool) {
+	for _, c := range []EventClass{ClassRace, ClassTT, ClassGroupRide, ClassWorkout, ClassOther} {
+		if strings.EqualFold(strings.TrimSpace(name), string(c)) {
+			return c, true
+		}
+	}
+	return "", false
+}
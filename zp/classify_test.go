@@ -0,0 +1,106 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultClassifier(t *testing.T) {
+	cases := []struct {
+		eventType string
+		title     string
+		expected  EventClass
+	}{
+		{"TYPE_RACE TYPE_RACE ", "Tour of Watopia Stage 4", ClassRace},
+		{"TYPE_RACE", "KISS Race", ClassRace},
+		{"TYPE_RACE", "ZHR Tuesday TT", ClassTT},
+		{"TYPE_RACE", "WTRL Team Time Trial - Zone 3", ClassTT},
+		{"TYPE_RACE", "WTRL TTT", ClassTT},
+		{"TYPE_RACE", "Watts Up Crit", ClassRace}, // "TT" inside a word doesn't count
+		{"TYPE_TT", "Chrono Tuesday", ClassTT},
+		{"TYPE_RIDE", "ZZRC SUB 2.0 Ride", ClassGroupRide},
+		{"TYPE_RIDE", "Tuesday TT recon", ClassGroupRide},
+		{"TYPE_GROUP_WORKOUT", "Sweet spot session", ClassWorkout},
+		{"", "Free ride", ClassOther},
+	}
+	for _, tc := range cases {
+		e := Event{EventType: tc.eventType, EventTitle: tc.title}
+		if class := DefaultClassifier(e); class != tc.expected {
+			t.Errorf("%q (%q): got %s, expected %s", tc.title, tc.eventType, class, tc.expected)
+		}
+	}
+
+	// Clubs can name their own TTs
+	classify := NewClassifier("Chase")
+	if class := classify(Event{EventType: "TYPE_RACE", EventTitle: "Sunday Chase Race"}); class != ClassTT {
+		t.Errorf("got %s for a chase race, expected TT", class)
+	}
+}
+
+func TestParseEventSet(t *testing.T) {
+	cases := []struct {
+		in       string
+		name     string
+		includes []EventClass
+		excludes []EventClass
+	}{
+		{in: "races", name: "", includes: []EventClass{ClassRace, ClassTT}, excludes: []EventClass{ClassGroupRide}},
+		{in: "tt", name: "TT", includes: []EventClass{ClassTT}, excludes: []EventClass{ClassRace}},
+		{in: "Race+Ride", name: "RaceRide", includes: []EventClass{ClassRace, ClassGroupRide}, excludes: []EventClass{ClassTT}},
+	}
+	for _, tc := range cases {
+		set, err := ParseEventSet(tc.in)
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if set.Name != tc.name {
+			t.Errorf("%q: got name %q, expected %q", tc.in, set.Name, tc.name)
+		}
+		for _, c := range tc.includes {
+			if !set.Includes(c) {
+				t.Errorf("%q should include %s", tc.in, c)
+			}
+		}
+		for _, c := range tc.excludes {
+			if set.Includes(c) {
+				t.Errorf("%q shouldn't include %s", tc.in, c)
+			}
+		}
+	}
+
+	if _, err := ParseEventSet("race+crits"); err == nil {
+		t.Errorf("expected an error for an unknown class")
+	}
+}
+
+func TestImportEventSets(t *testing.T) {
+	sets := []EventSet{Races, {Name: "TT", Classes: []EventClass{ClassTT}}, {Name: "Ride", Classes: []EventClass{ClassGroupRide}}}
+	periods := Windows(60)
+	c, _ := newFixtureClient(t,
+		WithEventSets(sets...),
+		WithPeriods(periods...),
+		WithReferenceTime(time.Date(2020, 4, 30, 0, 0, 0, 0, time.UTC)),
+	)
+
+	r, err := c.ImportRider(98588)
+	if err != nil {
+		t.Fatalf("ImportRider: %v", err)
+	}
+
+	l := Layout{EventSets: sets, Periods: periods, Durations: DefaultDurations}
+	checkGolden(t, "event_sets", l.Headers(), l.Row(r))
+}
+
+func TestRaceCountUsesClassifier(t *testing.T) {
+	// Pure TTs, which ZwiftPower doesn't type as races, still count as races
+	c, _ := newFixtureClient(t, WithClassifier(func(Event) EventClass { return ClassTT }))
+
+	r, err := c.ImportRider(98588)
+	if err != nil {
+		t.Fatalf("ImportRider: %v", err)
+	}
+	if r.Rides != 3 || r.Races != 3 {
+		t.Errorf("got %d rides and %d races, expected every ride to count as a race", r.Rides, r.Races)
+	}
+}
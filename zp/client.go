@@ -0,0 +1,471 @@
+package zp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the ZwiftPower site that a Client talks to unless told otherwise
+const DefaultBaseURL = "https://zwiftpower.com"
+
+const zpTeamPath = "/cache3/teams/%d_riders.json"
+const zpRiderPath = "/cache3/profile/%d_all.json"
+const zpEventPath = "/events.php?zid=%s"
+const zpProfilePath = "/profile.php?z=%d"
+
+// DefaultConcurrency is how many rider profiles a Client fetches at once unless told otherwise
+const DefaultConcurrency = 4
+
+// By default a Client makes no more than DefaultRateLimit requests per second on
+// average, in bursts of up to DefaultBurst
+const (
+	DefaultRateLimit = 2.0
+	DefaultBurst     = 4
+)
+
+// By default a Client retries a request that was throttled or hit a server error
+// DefaultRetries times, backing off from DefaultRetryDelay up to maxRetryDelay
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = time.Second
+	maxRetryDelay     = 30 * time.Second
+)
+
+// DefaultWindows are the rolling windows, in days, that a Client collects race power over
+// unless told otherwise
+var DefaultWindows = []int{30, 42, 60, 90}
+
+// Client fetches data from ZwiftPower (or anything that serves the same JSON)
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	cookies     []*http.Cookie
+	userAgent   string
+	concurrency int
+	limiter     *rateLimiter
+	retries     int
+	retryDelay  time.Duration
+	sleep       func(context.Context, time.Duration) error
+	cache       Cache
+	cacheTTL    time.Duration
+	refresh     bool
+	periods     []Period
+	clock       Clock
+	eventSets   []EventSet
+	classify    Classifier
+	durations   []PowerDuration
+	trend       TrendAnalyser
+	categories  CategoryEngine
+	weightBasis WeightBasis
+	weightCheck WeightCheck
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL points the client at a different server, e.g. a mirror or a local test server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient makes the client send its requests through hc
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithCookies adds cookies that are sent with every request
+func WithCookies(cookies ...*http.Cookie) Option {
+	return func(c *Client) {
+		c.cookies = append(c.cookies, cookies...)
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithConcurrency sets how many rider profiles are fetched in parallel during a team import
+func WithConcurrency(n int) Option {
+	return func(c *Client) {
+		if n < 1 {
+			n = 1
+		}
+		c.concurrency = n
+	}
+}
+
+// WithRateLimit limits the client to perSecond requests per second on average, allowing
+// bursts of up to burst requests. A rate of zero or less turns off rate limiting.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(c *Client) {
+		c.limiter = newRateLimiter(perSecond, burst)
+	}
+}
+
+// WithRetries sets how many times a throttled or failed request is retried, and the
+// delay before the first retry. Later retries back off exponentially.
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryDelay = delay
+	}
+}
+
+// WithCache keeps responses in cache. Cached responses younger than ttl are used
+// without asking ZwiftPower; older ones are revalidated with a conditional request.
+func WithCache(cache Cache, ttl time.Duration) Option {
+	return func(c *Client) {
+		c.cache = cache
+		c.cacheTTL = ttl
+	}
+}
+
+// WithRefresh ignores anything already in the cache, but still stores fresh responses in it
+func WithRefresh() Option {
+	return func(c *Client) {
+		c.refresh = true
+	}
+}
+
+// WithWindows sets the rolling windows, in days, that each rider's race power is
+// collected over. Windows that aren't positive are ignored.
+func WithWindows(days ...int) Option {
+	return func(c *Client) {
+		c.periods = Windows(days...)
+	}
+}
+
+// WithPeriods sets the periods that each rider's race power is collected over
+func WithPeriods(periods ...Period) Option {
+	return func(c *Client) {
+		c.periods = periods
+	}
+}
+
+// WithEventSets sets the groups of event classes that each rider's power is collected
+// for. Each set gets a power group for each period.
+func WithEventSets(sets ...EventSet) Option {
+	return func(c *Client) {
+		c.eventSets = sets
+	}
+}
+
+// WithClassifier sets how the client decides what class of event each event is
+func WithClassifier(classify Classifier) Option {
+	return func(c *Client) {
+		c.classify = classify
+	}
+}
+
+// WithTrend sets how each rider's trend is worked out. If the analyser doesn't have
+// a classifier, it uses the client's.
+func WithTrend(analyser TrendAnalyser) Option {
+	return func(c *Client) {
+		c.trend = analyser
+	}
+}
+
+// WithWeightBasis sets the weight that W/kg values in power groups are worked out from
+func WithWeightBasis(basis WeightBasis) Option {
+	return func(c *Client) {
+		c.weightBasis = basis
+	}
+}
+
+// WithWeightCheck sets which changes in riders' weight are flagged as anomalies
+func WithWeightCheck(check WeightCheck) Option {
+	return func(c *Client) {
+		c.weightCheck = check
+	}
+}
+
+// WithCategoryEngine sets how each rider's category is worked out. If the engine
+// doesn't have a classifier, it uses the client's.
+func WithCategoryEngine(engine CategoryEngine) Option {
+	return func(c *Client) {
+		c.categories = engine
+	}
+}
+
+// Clock tells a Client what time it is when working out which events fall in each period
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock is a Clock that is stopped at a particular time
+type FixedClock time.Time
+
+// Now is always the fixed time
+func (f FixedClock) Now() time.Time {
+	return time.Time(f)
+}
+
+// WithClock makes the client work out periods relative to the clock's time rather than
+// the system clock. Events after that time are ignored. Caching and rate limiting still
+// use the system clock.
+func WithClock(clock Clock) Option {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
+
+// WithReferenceTime works out periods relative to t instead of the time of the import,
+// so that reports for a past date can be reproduced
+func WithReferenceTime(t time.Time) Option {
+	return WithClock(FixedClock(t))
+}
+
+// WithDurations sets the durations that each rider's best power is collected for
+func WithDurations(durations ...PowerDuration) Option {
+	return func(c *Client) {
+		c.durations = durations
+	}
+}
+
+// CloudFrontCookies builds the signed cookies that ZwiftPower's CloudFront distribution expects
+func CloudFrontCookies(policy, signature, keyPairID string) []*http.Cookie {
+	return []*http.Cookie{
+		{Name: "CloudFront-Policy", Value: policy},
+		{Name: "CloudFront-Signature", Value: signature},
+		{Name: "CloudFront-Key-Pair-Id", Value: keyPairID},
+	}
+}
+
+// NewClient returns a Client for the live ZwiftPower site, adjusted by any options
+func NewClient(opts ...Option) (*Client, error) {
+	log.Printf("NewClient")
+	c := &Client{
+		baseURL:     DefaultBaseURL,
+		concurrency: DefaultConcurrency,
+		limiter:     newRateLimiter(DefaultRateLimit, DefaultBurst),
+		retries:     DefaultRetries,
+		retryDelay:  DefaultRetryDelay,
+		sleep:       sleep,
+		periods:     Windows(DefaultWindows...),
+		clock:       systemClock{},
+		eventSets:   []EventSet{Races},
+		classify:    DefaultClassifier,
+		trend:       DefaultTrendAnalyser,
+		categories:  DefaultCategoryEngine,
+		weightCheck: DefaultWeightCheck,
+		durations:   DefaultDurations,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.httpClient == nil {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		c.httpClient = &http.Client{
+			Jar: jar,
+		}
+	}
+
+	return c, nil
+}
+
+// Durations are the power durations that the client collects
+func (c *Client) Durations() []PowerDuration {
+	return c.durations
+}
+
+// BaseURL is the ZwiftPower site that the client talks to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+func (c *Client) teamURL(clubID int) string {
+	return c.baseURL + fmt.Sprintf(zpTeamPath, clubID)
+}
+
+func (c *Client) riderURL(riderID int) string {
+	return c.baseURL + fmt.Sprintf(zpRiderPath, riderID)
+}
+
+// getJSON fetches url and decodes it into v, unless v is nil, waiting for the rate
+// limiter and retrying if ZwiftPower throttles us or has a temporary problem. If the
+// client has a cache, a fresh enough cached response is used instead, and a stale one
+// is revalidated. Only responses that decode are cached, so that something like a login
+// page isn't served from the cache until it expires.
+func (c *Client) getJSON(ctx context.Context, url string, v interface{}) ([]byte, error) {
+	cached := c.cached(ctx, url)
+	if cached != nil {
+		decoded, err := decodeJSON(cached.Body, v)
+		if err != nil {
+			log.Printf("Ignoring cached %s: %v", url, err)
+			cached = nil
+		} else if time.Since(cached.Fetched) < c.cacheTTL {
+			setDecoded(v, decoded)
+			return cached.Body, nil
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.sleep(ctx, c.limiter.reserve()); err != nil {
+			return []byte{}, err
+		}
+
+		entry, wait, err := c.fetch(ctx, url, cached)
+		if err == nil {
+			decoded, err := decodeJSON(entry.Body, v)
+			if err != nil {
+				return []byte{}, &MalformedError{URL: url, Err: err}
+			}
+			if c.cache != nil {
+				if err := c.cache.Put(ctx, url, entry); err != nil {
+					log.Printf("Caching %s: %v", url, err)
+				}
+			}
+			setDecoded(v, decoded)
+			return entry.Body, nil
+		}
+
+		// If ZwiftPower asked us to wait, every request waits for the limiter, not just this one
+		paused := wait > 0 && c.limiter.pause(wait)
+		if wait < 0 || attempt >= c.retries {
+			return []byte{}, err
+		}
+		if paused {
+			log.Printf("Retrying %s, and holding back other requests, in %v: %v", url, wait, err)
+			continue
+		}
+
+		if wait == 0 {
+			wait = backoff(attempt, c.retryDelay, maxRetryDelay)
+		}
+		log.Printf("Retrying %s in %v: %v", url, wait, err)
+		if err := c.sleep(ctx, wait); err != nil {
+			return []byte{}, err
+		}
+	}
+}
+
+// decodeJSON decodes data into a new value of the type that v points to, so that nothing
+// is left over from what v held before. It returns nil if v is nil.
+func decodeJSON(data []byte, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	decoded := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+	return decoded, json.Unmarshal(data, decoded)
+}
+
+// setDecoded replaces what v points to with a value from decodeJSON
+func setDecoded(v, decoded interface{}) {
+	if v == nil {
+		return
+	}
+	reflect.ValueOf(v).Elem().Set(reflect.ValueOf(decoded).Elem())
+}
+
+// cached returns the cache entry for url, or nil if there isn't one we can use
+func (c *Client) cached(ctx context.Context, url string) *CacheEntry {
+	if c.cache == nil || c.refresh {
+		return nil
+	}
+
+	entry, err := c.cache.Get(ctx, url)
+	if err != nil {
+		log.Printf("Reading %s from cache: %v", url, err)
+		return nil
+	}
+	return entry
+}
+
+// fetch makes a single request for url, which is conditional if we have a cached
+// response. If it fails, wait says how long to wait before trying again: zero to
+// back off as normal, or negative if it's not worth retrying.
+func (c *Client) fetch(ctx context.Context, url string, cached *CacheEntry) (entry *CacheEntry, wait time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Cancelled, so there's no point retrying
+			return nil, -1, ctx.Err()
+		}
+		return nil, 0, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		revalidated := *cached
+		revalidated.Fetched = time.Now()
+		return &revalidated, 0, nil
+	}
+
+	if resp.StatusCode != 200 {
+		start, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		err = newStatusError(resp.StatusCode, url, start)
+		if !retryable(resp.StatusCode) {
+			return nil, -1, err
+		}
+		wait := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if wait > maxRetryDelay {
+			// Don't tie the import up for hours
+			log.Printf("%s asked us to wait %v, waiting %v", url, wait, maxRetryDelay)
+			wait = maxRetryDelay
+		}
+		return nil, wait, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &CacheEntry{
+		Body:         body,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Fetched:      time.Now(),
+	}, 0, nil
+}
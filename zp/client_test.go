@@ -0,0 +1,243 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedServer responds with each of statuses in turn, then 200 with body
+func scriptedServer(t *testing.T, body string, statuses ...int) (*httptest.Server, *int) {
+	t.Helper()
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= len(statuses) {
+			if statuses[calls-1] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "7")
+			}
+			w.WriteHeader(statuses[calls-1])
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// recordSleeps stops the client really sleeping, and records how long it wanted to sleep for
+func recordSleeps(c *Client) *[]time.Duration {
+	var sleeps []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		if d > 0 {
+			sleeps = append(sleeps, d)
+		}
+		return ctx.Err()
+	}
+	return &sleeps
+}
+
+func TestGetJSONRetries(t *testing.T) {
+	cases := []struct {
+		name     string
+		statuses []int
+		retries  int
+		calls    int
+		fails    bool
+		sleeps   int
+	}{
+		{name: "ok", calls: 1, retries: 3},
+		{name: "server error", statuses: []int{503, 502}, retries: 3, calls: 3, sleeps: 2},
+		{name: "not found", statuses: []int{404}, retries: 3, calls: 1, fails: true},
+		{name: "forbidden", statuses: []int{403}, retries: 3, calls: 1, fails: true},
+		{name: "gives up", statuses: []int{500, 500, 500}, retries: 2, calls: 3, fails: true, sleeps: 2},
+		{name: "no retries", statuses: []int{500}, retries: 0, calls: 1, fails: true},
+	}
+
+	for _, tc := range cases {
+		srv, calls := scriptedServer(t, `{"data":[]}`, tc.statuses...)
+		c, err := NewClient(WithBaseURL(srv.URL), WithRetries(tc.retries, time.Second), WithRateLimit(0, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sleeps := recordSleeps(c)
+
+		body, err := c.getJSON(context.Background(), srv.URL+"/x", nil)
+		if tc.fails != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if !tc.fails && string(body) != `{"data":[]}` {
+			t.Errorf("%s: unexpected body %q", tc.name, body)
+		}
+		if *calls != tc.calls {
+			t.Errorf("%s: made %d calls, expected %d", tc.name, *calls, tc.calls)
+		}
+		if len(*sleeps) != tc.sleeps {
+			t.Errorf("%s: slept %v, expected %d sleeps", tc.name, *sleeps, tc.sleeps)
+		}
+		for i, d := range *sleeps {
+			max := time.Second << uint(i)
+			if d < max/2 || d > max {
+				t.Errorf("%s: backoff %d was %v, expected between %v and %v", tc.name, i, d, max/2, max)
+			}
+		}
+	}
+}
+
+func TestGetJSONRetryAfter(t *testing.T) {
+	srv, calls := scriptedServer(t, `{}`, http.StatusTooManyRequests)
+	c, err := NewClient(WithBaseURL(srv.URL), WithRateLimit(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sleeps := recordSleeps(c)
+
+	if _, err := c.getJSON(context.Background(), srv.URL+"/x", nil); err != nil {
+		t.Fatalf("getJSON: %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("made %d calls, expected 2", *calls)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != 7*time.Second {
+		t.Errorf("slept %v, expected to honour Retry-After of 7s", *sleeps)
+	}
+}
+
+func TestGetJSONRetryAfterTooLong(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	c, err := NewClient(WithBaseURL(srv.URL), WithRateLimit(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sleeps := recordSleeps(c)
+
+	if _, err := c.getJSON(context.Background(), srv.URL+"/x", nil); err != nil {
+		t.Fatalf("getJSON: %v", err)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != maxRetryDelay {
+		t.Errorf("slept %v, expected to wait no more than %v", *sleeps, maxRetryDelay)
+	}
+}
+
+func TestGetJSONRetryAfterHoldsBackOtherRequests(t *testing.T) {
+	srv, calls := scriptedServer(t, `{}`, http.StatusTooManyRequests)
+	c, err := NewClient(WithBaseURL(srv.URL), WithRateLimit(100, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sleeps := recordSleeps(c)
+
+	if _, err := c.getJSON(context.Background(), srv.URL+"/x", nil); err != nil {
+		t.Fatalf("getJSON: %v", err)
+	}
+	if *calls != 2 || len(*sleeps) != 1 || (*sleeps)[0] < 6*time.Second {
+		t.Errorf("made %d calls after sleeping %v, expected to wait for Retry-After of 7s", *calls, *sleeps)
+	}
+
+	// Requests from other workers have to wait too
+	if wait := c.limiter.reserve(); wait <= 0 {
+		t.Errorf("expected the next request to be held back")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		header   string
+		expected time.Duration
+	}{
+		{header: "", expected: 0},
+		{header: "120", expected: 2 * time.Minute},
+		{header: "-3", expected: 0},
+		{header: "Thu, 01 Apr 2021 12:00:30 GMT", expected: 30 * time.Second},
+		{header: "Thu, 01 Apr 2021 11:00:00 GMT", expected: 0},
+		{header: "soon", expected: 0},
+	}
+
+	for _, tc := range cases {
+		if got := retryAfter(tc.header, now); got != tc.expected {
+			t.Errorf("Retry-After %q: got %v, expected %v", tc.header, got, tc.expected)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	rl := newRateLimiter(2, 2)
+	rl.now = func() time.Time { return now }
+
+	// The burst is available straight away, then we have to wait half a second per token
+	expected := []time.Duration{0, 0, 500 * time.Millisecond, time.Second}
+	for i, e := range expected {
+		if got := rl.reserve(); got != e {
+			t.Errorf("reservation %d: wait %v, expected %v", i, got, e)
+		}
+	}
+
+	// After a while the bucket refills, but never beyond the burst size
+	now = now.Add(time.Minute)
+	expected = []time.Duration{0, 0, 500 * time.Millisecond}
+	for i, e := range expected {
+		if got := rl.reserve(); got != e {
+			t.Errorf("reservation %d after refill: wait %v, expected %v", i, got, e)
+		}
+	}
+
+	// Being paused holds back the next reservation, and the ones queued behind it
+	now = now.Add(time.Minute)
+	rl.pause(10 * time.Second)
+	expected = []time.Duration{10 * time.Second, 10*time.Second + 500*time.Millisecond}
+	for i, e := range expected {
+		if got := rl.reserve(); got != e {
+			t.Errorf("reservation %d after pause: wait %v, expected %v", i, got, e)
+		}
+	}
+
+	var off *rateLimiter
+	if got := off.reserve(); got != 0 {
+		t.Errorf("disabled limiter made us wait %v", got)
+	}
+	if off.pause(time.Second) {
+		t.Errorf("disabled limiter can't be paused")
+	}
+}
+
+func TestGetJSONCancelledWhileWaiting(t *testing.T) {
+	srv, calls := scriptedServer(t, `{}`, http.StatusServiceUnavailable)
+	c, err := NewClient(WithBaseURL(srv.URL), WithRateLimit(0, 0), WithRetries(3, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = c.getJSON(ctx, srv.URL+"/x", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, expected the deadline to be exceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("took %v to notice the context was done", time.Since(start))
+	}
+	if *calls != 1 {
+		t.Errorf("made %d calls, expected 1", *calls)
+	}
+}
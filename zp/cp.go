@@ -0,0 +1,206 @@
+package zp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// CPModelType is a critical power model that can be fitted to a rider's best efforts
+type CPModelType int
+
+// These are the critical power models we know how to fit
+const (
+	NoCP     CPModelType = iota
+	CP2Param             // P = CP + W'/t
+	CP3Param             // P = CP + W'/(t + W'/(Pmax - CP)), after Morton
+)
+
+// MinCP2Seconds is the shortest effort that the 2-parameter model is fitted to, because
+// it doesn't describe short, sprint efforts
+const MinCP2Seconds = 120
+
+// ErrCPFit means a critical power model couldn't be fitted, usually because there
+// weren't enough efforts
+var ErrCPFit = errors.New("can't fit critical power model")
+
+// Effort is a rider's best power for a duration
+type Effort struct {
+	Seconds int
+	Watts   float64
+}
+
+// CPFit is a critical power model fitted to a rider's efforts
+type CPFit struct {
+	CP      float64 // Critical power in watts
+	WPrime  float64 // W', the work that can be done above CP, in joules
+	Pmax    float64 // Maximum power in watts, from the 3-parameter model only
+	RMSE    float64 // Root mean square difference between the model and the efforts, in watts
+	R2      float64 // The fraction of the variation in the efforts' power that the model explains
+	Efforts int     // The number of efforts the model was fitted to
+}
+
+// Efforts lists the group's best power for each duration, shortest first
+func (g riderPowerGroup) Efforts() []Effort {
+	var output []Effort
+	for seconds, best := range g.Watts {
+		if best.Value > 0 {
+			output = append(output, Effort{Seconds: seconds, Watts: best.Value})
+		}
+	}
+	sort.Slice(output, func(i, j int) bool { return output[i].Seconds < output[j].Seconds })
+	return output
+}
+
+// FitCP fits a critical power model to the efforts by least squares. The 2-parameter
+// model needs at least two efforts of MinCP2Seconds or longer, and the 3-parameter
+// model needs at least three efforts.
+func FitCP(model CPModelType, efforts []Effort) (CPFit, error) {
+	switch model {
+	case CP2Param:
+		var long []Effort
+		for _, e := range efforts {
+			if e.Seconds >= MinCP2Seconds {
+				long = append(long, e)
+			}
+		}
+		return fitCP2(long)
+	case CP3Param:
+		return fitCP3(efforts)
+	}
+	return CPFit{}, fmt.Errorf("%w: unknown model %d", ErrCPFit, model)
+}
+
+func fitCP2(efforts []Effort) (CPFit, error) {
+	if distinctDurations(efforts) < 2 {
+		return CPFit{}, fmt.Errorf("%w: need at least 2 efforts of %d seconds or more, have %d", ErrCPFit, MinCP2Seconds, len(efforts))
+	}
+
+	cp, wPrime, _ := fitLinear(efforts, 0)
+	if cp <= 0 || wPrime <= 0 {
+		return CPFit{}, fmt.Errorf("%w: efforts don't fit the model (CP %.0f, W' %.0f)", ErrCPFit, cp, wPrime)
+	}
+	return withQuality(CPFit{CP: cp, WPrime: wPrime}, efforts, 0), nil
+}
+
+func fitCP3(efforts []Effort) (CPFit, error) {
+	if distinctDurations(efforts) < 3 {
+		return CPFit{}, fmt.Errorf("%w: need at least 3 efforts, have %d", ErrCPFit, len(efforts))
+	}
+
+	// For a fixed k = W'/(Pmax - CP) the model is linear, so search over k for the
+	// best linear fit: first on a coarse logarithmic grid, then by golden section
+	// between the neighbours of the best grid point.
+	const steps = 200
+	lo, hi := math.Log(0.1), math.Log(1000.0)
+	sseAt := func(logK float64) float64 {
+		cp, wPrime, sse := fitLinear(efforts, math.Exp(logK))
+		if cp <= 0 || wPrime <= 0 {
+			return math.Inf(1)
+		}
+		return sse
+	}
+
+	best, bestSSE := lo, math.Inf(1)
+	for i := 0; i <= steps; i++ {
+		logK := lo + (hi-lo)*float64(i)/steps
+		if sse := sseAt(logK); sse < bestSSE {
+			best, bestSSE = logK, sse
+		}
+	}
+	if math.IsInf(bestSSE, 1) {
+		return CPFit{}, fmt.Errorf("%w: efforts don't fit the model", ErrCPFit)
+	}
+
+	step := (hi - lo) / steps
+	a, b := best-step, best+step
+	ratio := (math.Sqrt(5) - 1) / 2
+	for i := 0; i < 60; i++ {
+		c, d := b-ratio*(b-a), a+ratio*(b-a)
+		if sseAt(c) < sseAt(d) {
+			b = d
+		} else {
+			a = c
+		}
+	}
+	if sse := sseAt((a + b) / 2); sse < bestSSE {
+		best = (a + b) / 2
+	}
+
+	k := math.Exp(best)
+	cp, wPrime, _ := fitLinear(efforts, k)
+	return withQuality(CPFit{CP: cp, WPrime: wPrime, Pmax: cp + wPrime/k}, efforts, k), nil
+}
+
+// fitLinear fits P = CP + W'/(t + k) by ordinary least squares, and returns the sum of
+// the squared errors
+func fitLinear(efforts []Effort, k float64) (cp, wPrime, sse float64) {
+	n := float64(len(efforts))
+	var sumX, sumY, sumXX, sumXY float64
+	for _, e := range efforts {
+		x := 1 / (float64(e.Seconds) + k)
+		sumX += x
+		sumY += e.Watts
+		sumXX += x * x
+		sumXY += x * e.Watts
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, 0, math.Inf(1)
+	}
+	wPrime = (n*sumXY - sumX*sumY) / denominator
+	cp = (sumY - wPrime*sumX) / n
+
+	for _, e := range efforts {
+		diff := e.Watts - (cp + wPrime/(float64(e.Seconds)+k))
+		sse += diff * diff
+	}
+	return cp, wPrime, sse
+}
+
+// withQuality fills in how well fit, with its k, matches the efforts
+func withQuality(fit CPFit, efforts []Effort, k float64) CPFit {
+	var mean float64
+	for _, e := range efforts {
+		mean += e.Watts
+	}
+	mean /= float64(len(efforts))
+
+	var sse, sst float64
+	for _, e := range efforts {
+		diff := e.Watts - (fit.CP + fit.WPrime/(float64(e.Seconds)+k))
+		sse += diff * diff
+		sst += (e.Watts - mean) * (e.Watts - mean)
+	}
+
+	fit.Efforts = len(efforts)
+	fit.RMSE = math.Sqrt(sse / float64(len(efforts)))
+	if sst > 0 {
+		fit.R2 = 1 - sse/sst
+	}
+	return fit
+}
+
+func distinctDurations(efforts []Effort) int {
+	seen := map[int]bool{}
+	for _, e := range efforts {
+		seen[e.Seconds] = true
+	}
+	return len(seen)
+}
+
+// ParseCPModel reads "none", "2" or "3"
+func ParseCPModel(s string) (CPModelType, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return NoCP, nil
+	case "2":
+		return CP2Param, nil
+	case "3":
+		return CP3Param, nil
+	}
+	return NoCP, fmt.Errorf("unknown critical power model %q: expected none, 2 or 3", s)
+}
@@ -0,0 +1,117 @@
+package zp
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// curve generates efforts from a 3-parameter model. With pmax of 0 it uses the 2-parameter model.
+func curve(cp, wPrime, pmax float64, seconds ...int) []Effort {
+	var k float64
+	if pmax > 0 {
+		k = wPrime / (pmax - cp)
+	}
+	efforts := make([]Effort, len(seconds))
+	for i, s := range seconds {
+		efforts[i] = Effort{Seconds: s, Watts: cp + wPrime/(float64(s)+k)}
+	}
+	return efforts
+}
+
+func near(got, expected, tolerance float64) bool {
+	return math.Abs(got-expected) <= tolerance
+}
+
+func TestFitCP2(t *testing.T) {
+	// The sprint is ignored, so the fit is exact
+	efforts := append(curve(250, 20000, 0, 120, 300, 1200), Effort{Seconds: 5, Watts: 900})
+	fit, err := FitCP(CP2Param, efforts)
+	if err != nil {
+		t.Fatalf("FitCP: %v", err)
+	}
+	if !near(fit.CP, 250, 1e-6) || !near(fit.WPrime, 20000, 1e-3) || fit.Pmax != 0 {
+		t.Errorf("unexpected fit %+v", fit)
+	}
+	if fit.Efforts != 3 || !near(fit.RMSE, 0, 1e-6) || !near(fit.R2, 1, 1e-9) {
+		t.Errorf("unexpected fit quality %+v", fit)
+	}
+}
+
+func TestFitCP3(t *testing.T) {
+	efforts := curve(250, 20000, 1000, 5, 15, 30, 60, 120, 300, 1200)
+	fit, err := FitCP(CP3Param, efforts)
+	if err != nil {
+		t.Fatalf("FitCP: %v", err)
+	}
+	if !near(fit.CP, 250, 0.5) || !near(fit.WPrime, 20000, 50) || !near(fit.Pmax, 1000, 5) {
+		t.Errorf("unexpected fit %+v", fit)
+	}
+	if fit.Efforts != 7 || fit.RMSE > 0.5 || fit.R2 < 0.999 {
+		t.Errorf("unexpected fit quality %+v", fit)
+	}
+
+	// Noisy efforts still fit, but not as well
+	efforts[2].Watts += 40
+	efforts[5].Watts -= 15
+	noisy, err := FitCP(CP3Param, efforts)
+	if err != nil {
+		t.Fatalf("FitCP: %v", err)
+	}
+	if noisy.RMSE <= fit.RMSE || noisy.R2 >= fit.R2 {
+		t.Errorf("expected a worse fit for noisy efforts: %+v", noisy)
+	}
+}
+
+func TestFitCPErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		model   CPModelType
+		efforts []Effort
+	}{
+		{"no efforts", CP2Param, nil},
+		{"only sprints", CP2Param, curve(250, 20000, 0, 5, 15, 30, 60)},
+		{"one duration", CP2Param, []Effort{{300, 320}, {300, 310}}},
+		{"too few for 3", CP3Param, curve(250, 20000, 1000, 60, 300)},
+		{"power rises with time", CP2Param, []Effort{{120, 200}, {1200, 300}}},
+		{"no model", NoCP, curve(250, 20000, 0, 120, 300, 1200)},
+	}
+	for _, tc := range cases {
+		if _, err := FitCP(tc.model, tc.efforts); !errors.Is(err, ErrCPFit) {
+			t.Errorf("%s: got %v, expected ErrCPFit", tc.name, err)
+		}
+	}
+}
+
+func TestParseCPModel(t *testing.T) {
+	for in, expected := range map[string]CPModelType{"": NoCP, "none": NoCP, "2": CP2Param, "3": CP3Param} {
+		if m, err := ParseCPModel(in); err != nil || m != expected {
+			t.Errorf("%q: got %v, %v, expected %v", in, m, err, expected)
+		}
+	}
+	if _, err := ParseCPModel("4"); err == nil {
+		t.Errorf("expected an error for a 4-parameter model")
+	}
+}
+
+func TestCPColumns(t *testing.T) {
+	c, _ := newFixtureClient(t, WithWindows(365), WithReferenceTime(time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC)))
+
+	r, err := c.ImportRider(1261784)
+	if err != nil {
+		t.Fatalf("ImportRider: %v", err)
+	}
+	r.Weight = 56.3
+
+	var rows [][]string
+	for _, model := range []CPModelType{CP2Param, CP3Param} {
+		l := Layout{Periods: Windows(30, 365), Durations: DefaultDurations, CP: model}
+		headers, row := l.Headers(), l.Row(r)
+		if len(headers) != len(row) {
+			t.Errorf("model %d: %d headers for %d values", model, len(headers), len(row))
+		}
+		rows = append(rows, headers, row)
+	}
+	checkGolden(t, "cp", rows...)
+}
@@ -0,0 +1,62 @@
+package zp
+
+import (
+	"fmt"
+	"time"
+)
+
+// PowerDuration is a duration that ZwiftPower reports a rider's best power for, along
+// with the keys it uses for the watts and W/kg in each event
+type PowerDuration struct {
+	Seconds int
+	WKey    string // e.g. "w1200"
+	WkgKey  string // e.g. "wkg1200"
+}
+
+// Duration describes the power duration of this many seconds, using ZwiftPower's usual keys
+func Duration(seconds int) PowerDuration {
+	return PowerDuration{
+		Seconds: seconds,
+		WKey:    fmt.Sprintf("w%d", seconds),
+		WkgKey:  fmt.Sprintf("wkg%d", seconds),
+	}
+}
+
+// Durations describes each of these durations, which are rounded to the nearest second
+func Durations(ds ...time.Duration) []PowerDuration {
+	output := make([]PowerDuration, len(ds))
+	for i, d := range ds {
+		output[i] = Duration(int(d.Round(time.Second) / time.Second))
+	}
+	return output
+}
+
+// DefaultDurations are the power durations that a Client collects unless told otherwise,
+// longest first
+var DefaultDurations = []PowerDuration{
+	Duration(1200),
+	Duration(300),
+	Duration(120),
+	Duration(60),
+	Duration(30),
+	Duration(15),
+	Duration(5),
+}
+
+// Label names the duration for column headers, e.g. "20Min" or "15Sec"
+func (d PowerDuration) Label() string {
+	if d.Seconds >= 60 && d.Seconds%60 == 0 {
+		return fmt.Sprintf("%dMin", d.Seconds/60)
+	}
+	return fmt.Sprintf("%dSec", d.Seconds)
+}
+
+// Watts is the rider's best power for duration d in the event
+func (e Event) Watts(d PowerDuration) PowerValue {
+	return e.values[d.WKey]
+}
+
+// Wkg is the rider's best W/kg for duration d in the event
+func (e Event) Wkg(d PowerDuration) PowerValue {
+	return e.values[d.WkgKey]
+}
@@ -0,0 +1,56 @@
+package zp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDurationLabel(t *testing.T) {
+	cases := map[int]string{
+		5:    "5Sec",
+		10:   "10Sec",
+		90:   "90Sec",
+		60:   "1Min",
+		600:  "10Min",
+		1200: "20Min",
+	}
+	for seconds, expected := range cases {
+		if label := Duration(seconds).Label(); label != expected {
+			t.Errorf("%d seconds labelled %q, expected %q", seconds, label, expected)
+		}
+	}
+}
+
+func TestDurations(t *testing.T) {
+	ds := Durations(10*time.Minute, 10*time.Second)
+	if len(ds) != 2 || ds[0] != (PowerDuration{600, "w600", "wkg600"}) || ds[1] != (PowerDuration{10, "w10", "wkg10"}) {
+		t.Errorf("unexpected durations %v", ds)
+	}
+}
+
+func TestExtraDurations(t *testing.T) {
+	c, _ := newFixtureClient(t)
+	ds := append(Durations(10*time.Minute, 10*time.Second), Duration(1200))
+
+	events, err := c.fetchEvents(context.Background(), 98588)
+	if err != nil {
+		t.Fatalf("fetchEvents: %v", err)
+	}
+
+	g := riderPowerGroup{Events: Races, Period: Rolling(30)}
+	for _, e := range events {
+		processPowerGroup(&g, e, DefaultClassifier(e), e.EventDate, ds)
+	}
+
+	expected := map[int][2]float64{600: {218, 3.7}, 10: {581, 9.8}, 1200: {201, 3.4}}
+	for seconds, v := range expected {
+		if g.Watts[seconds].Value != v[0] || g.Wpkg[seconds].Value != v[1] {
+			t.Errorf("%d seconds: got %v W and %v W/kg, expected %v", seconds, g.Watts[seconds].Value, g.Wpkg[seconds].Value, v)
+		}
+	}
+
+	l := Layout{Periods: Windows(30), Durations: ds}
+	r := RiderDetail{Name: "Liz Rice", Zwid: 98588, PowerGroups: []riderPowerGroup{g}}
+	checkGolden(t, "durations", l.Headers(), l.Row(r))
+}
@@ -0,0 +1,135 @@
+package zp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// These errors describe the ways that getting data from ZwiftPower can fail. Check
+// for them with errors.Is.
+var (
+	// ErrAuthExpired means ZwiftPower rejected our credentials, usually because
+	// the CloudFront cookies have expired
+	ErrAuthExpired = errors.New("ZwiftPower authentication expired")
+	// ErrNotFound means there is no such team or rider
+	ErrNotFound = errors.New("not found on ZwiftPower")
+	// ErrRateLimited means ZwiftPower was still throttling us after all our retries
+	ErrRateLimited = errors.New("rate limited by ZwiftPower")
+	// ErrPrivateProfile means the rider has hidden their profile
+	ErrPrivateProfile = errors.New("ZwiftPower profile is private")
+	// ErrMalformed means ZwiftPower sent us data we couldn't decode
+	ErrMalformed = errors.New("malformed ZwiftPower data")
+)
+
+// StatusError is returned when ZwiftPower responds with an unexpected HTTP status.
+// It unwraps to one of the sentinel errors above if we know what the status means.
+type StatusError struct {
+	StatusCode int
+	URL        string
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("unexpected status %d for %s: %v", e.StatusCode, e.URL, e.Err)
+	}
+	return fmt.Sprintf("unexpected status %d for %s", e.StatusCode, e.URL)
+}
+
+// Unwrap gives access to the sentinel error, if there is one
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// newStatusError works out what a failed response means. body is (the start of) the response body.
+func newStatusError(statusCode int, url string, body []byte) *StatusError {
+	e := &StatusError{StatusCode: statusCode, URL: url}
+	switch statusCode {
+	case http.StatusUnauthorized:
+		e.Err = ErrAuthExpired
+	case http.StatusForbidden:
+		// When the signed cookies are missing or out of date, CloudFront itself refuses
+		// the request with an XML error. Any other 403 is from ZwiftPower.
+		if bytes.Contains(body, []byte("<Error>")) {
+			e.Err = ErrAuthExpired
+		}
+	case http.StatusNotFound:
+		e.Err = ErrNotFound
+	case http.StatusTooManyRequests:
+		e.Err = ErrRateLimited
+	}
+	return e
+}
+
+// MalformedError is returned when a response from ZwiftPower can't be decoded. It matches ErrMalformed.
+type MalformedError struct {
+	URL string
+	Err error
+}
+
+func (e *MalformedError) Error() string {
+	return fmt.Sprintf("%v from %s: %v", ErrMalformed, e.URL, e.Err)
+}
+
+// Unwrap gives access to the decoding error
+func (e *MalformedError) Unwrap() error {
+	return e.Err
+}
+
+// Is lets errors.Is(err, ErrMalformed) find a MalformedError
+func (e *MalformedError) Is(target error) bool {
+	return target == ErrMalformed
+}
+
+// RiderError records why one rider's data couldn't be imported
+type RiderError struct {
+	Zwid int
+	Name string
+	Err  error
+}
+
+func (e *RiderError) Error() string {
+	return fmt.Sprintf("loading data for %s (%d): %v", e.Name, e.Zwid, e.Err)
+}
+
+// Unwrap gives access to the underlying error
+func (e *RiderError) Unwrap() error {
+	return e.Err
+}
+
+// ImportError is returned alongside the riders that were imported successfully when
+// some of the team couldn't be imported
+type ImportError struct {
+	Riders   int // The number of riders we tried to import
+	Failures []*RiderError
+}
+
+func (e *ImportError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = f.Error()
+	}
+	return fmt.Sprintf("%d of %d riders failed: %s", len(e.Failures), e.Riders, strings.Join(msgs, "; "))
+}
+
+// Is reports whether any of the failures matches target
+func (e *ImportError) Is(target error) bool {
+	for _, f := range e.Failures {
+		if errors.Is(f, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// FailedIDs lists the Zwift IDs of the riders that couldn't be imported
+func (e *ImportError) FailedIDs() []int {
+	ids := make([]int, len(e.Failures))
+	for i, f := range e.Failures {
+		ids[i] = f.Zwid
+	}
+	return ids
+}
@@ -0,0 +1,93 @@
+package zp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const cloudFrontDenied = `<?xml version="1.0" encoding="UTF-8"?><Error><Code>AccessDenied</Code><Message>Access denied</Message></Error>`
+
+// statusServer answers every request with status and body
+func statusServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestImportErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		body     string
+		team     bool
+		expected error
+	}{
+		{name: "private profile", status: 403, body: "Forbidden", expected: ErrPrivateProfile},
+		{name: "cookies expired", status: 403, body: cloudFrontDenied, expected: ErrAuthExpired},
+		{name: "unauthorized", status: 401, expected: ErrAuthExpired},
+		{name: "no such rider", status: 404, expected: ErrNotFound},
+		{name: "throttled", status: 429, expected: ErrRateLimited},
+		{name: "malformed profile", status: 200, body: `{"data":[`, expected: ErrMalformed},
+		{name: "team forbidden", status: 403, body: "Forbidden", team: true, expected: ErrAuthExpired},
+		{name: "no such team", status: 404, team: true, expected: ErrNotFound},
+		{name: "malformed team", status: 200, body: `<html>`, team: true, expected: ErrMalformed},
+	}
+
+	for _, tc := range cases {
+		srv := statusServer(t, tc.status, tc.body)
+		c, err := NewClient(WithBaseURL(srv.URL), WithRetries(0, time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tc.team {
+			_, err = c.ImportTeam(1234, 0)
+		} else {
+			_, err = c.ImportRider(98588)
+		}
+
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("%s: got %v, expected %v", tc.name, err, tc.expected)
+		}
+
+		for _, other := range []error{ErrAuthExpired, ErrNotFound, ErrRateLimited, ErrPrivateProfile, ErrMalformed} {
+			if other != tc.expected && errors.Is(err, other) {
+				t.Errorf("%s: %v also matches %v", tc.name, err, other)
+			}
+		}
+
+		var statusErr *StatusError
+		if tc.status != 200 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tc.status) {
+			t.Errorf("%s: expected a StatusError with status %d, got %v", tc.name, tc.status, err)
+		}
+	}
+}
+
+func TestMalformedErrorUnwraps(t *testing.T) {
+	srv := statusServer(t, 200, `{"data":[`)
+	c, err := NewClient(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.ImportRider(98588)
+
+	var malformed *MalformedError
+	if !errors.As(err, &malformed) || malformed.URL != srv.URL+"/cache3/profile/98588_all.json" {
+		t.Fatalf("expected a MalformedError for the profile URL, got %v", err)
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+		t.Errorf("expected to find the JSON error in %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RiderHistory returns every event on the rider's ZwiftPower profile, oldest first, with EventDate filled in
+func (c *Client) RiderHistory(ctx context.Context, zwid int) ([]Event, error) {
+	events, err := c.fetchEvents(ctx, zwid)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventDate.Before(events[j].EventDate)
+	})
+	return events, nil
+}
+
+// fetchEvents gets and decodes the events on the rider's profile, in the order ZwiftPower sends them
+func (c *Client) fetchEvents(ctx context.Context, zwid int) ([]Event, error) {
+	var r riderEvents
+	_, err := c.getJSON(ctx, c.riderURL(zwid), &r)
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusForbidden && statusErr.Err == nil {
+			// We're allowed in, just not to see this rider
+			statusErr.Err = ErrPrivateProfile
+		}
+		return nil, err
+	}
+
+	for i := range r.Data {
+		r.Data[i].EventDate = time.Unix(int64(r.Data[i].EventDateSecs), 0)
+	}
+	return r.Data, nil
+}
+
+// IsRace reports whether ZwiftPower counts this event as a race
+func (e Event) IsRace() bool {
+	return strings.Contains(e.EventType, "RACE")
+}
+
+// EventFilter decides whether to keep an event
+type EventFilter func(Event) bool
+
+// FilterEvents returns the events that pass all the filters, in their original order
+func FilterEvents(events []Event, filters ...EventFilter) []Event {
+	var output []Event
+
+events:
+	for _, e := range events {
+		for _, keep := range filters {
+			if !keep(e) {
+				continue events
+			}
+		}
+		output = append(output, e)
+	}
+
+	return output
+}
+
+// Between keeps events on or after from and before to. A zero time leaves that end of the range open.
+func Between(from, to time.Time) EventFilter {
+	return func(e Event) bool {
+		if !from.IsZero() && e.EventDate.Before(from) {
+			return false
+		}
+		if !to.IsZero() && !e.EventDate.Before(to) {
+			return false
+		}
+		return true
+	}
+}
+
+// RacesOnly keeps races
+func RacesOnly() EventFilter {
+	return Event.IsRace
+}
+
+// OfType keeps events whose ZwiftPower type includes eventType, e.g. "TYPE_RIDE"
+func OfType(eventType string) EventFilter {
+	return func(e Event) bool {
+		return strings.Contains(e.EventType, eventType)
+	}
+}
+
+// EventColumnHeaders are the headers for the rows from Event.Strings, with the watts and
+// W/kg for each of the durations
+func EventColumnHeaders(durations []PowerDuration) []string {
+	output := []string{"Date", "Event", "Type", "AvgWpkg", "FTP", "WFTP"}
+	for _, d := range durations {
+		output = append(output, "W"+d.Label(), "Wpkg"+d.Label())
+	}
+	return append(output, "Weight")
+}
+
+// Strings turns an event into []string, matching EventColumnHeaders for the same durations
+func (e Event) Strings(durations []PowerDuration) []string {
+	date := ""
+	if e.EventDateSecs != 0 {
+		date = e.EventDate.Format("2006-01-02")
+	}
+
+	output := []string{
+		date,
+		e.EventTitle,
+		eventTypeLabel(e.EventType),
+		strconv.FormatFloat(e.AvgWkg.Value, 'f', 1, 64),
+		strconv.FormatFloat(e.WkgFtp.Value, 'f', 1, 64),
+		strconv.Itoa(int(e.WFtp.Value)),
+	}
+
+	for _, d := range durations {
+		output = append(output, strconv.Itoa(int(e.Watts(d).Value)), strconv.FormatFloat(e.Wkg(d).Value, 'f', 1, 64))
+	}
+
+	return append(output, strconv.FormatFloat(e.Weight.Value, 'f', 1, 64))
+}
+
+// eventTypeLabel tidies up ZwiftPower's event type, which often repeats itself, e.g. "TYPE_RACE TYPE_RACE "
+func eventTypeLabel(eventType string) string {
+	var types []string
+	seen := map[string]bool{}
+	for _, t := range strings.Fields(eventType) {
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	return strings.Join(types, " ")
+}
@@ -0,0 +1,129 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRiderHistory(t *testing.T) {
+	c, _ := newFixtureClient(t)
+
+	events, err := c.RiderHistory(context.Background(), 98588)
+	if err != nil {
+		t.Fatalf("RiderHistory: %v", err)
+	}
+
+	// Oldest first, and the event with no date counts as the oldest of all
+	titles := []string{"KISS Race", "Tour of Watopia Stage 4", "ZZRC SUB 2.0 Ride"}
+	if len(events) != len(titles) {
+		t.Fatalf("got %d events, expected %d", len(events), len(titles))
+	}
+	for i, title := range titles {
+		if events[i].EventTitle != title {
+			t.Errorf("event %d is %q, expected %q", i, events[i].EventTitle, title)
+		}
+	}
+
+	if !events[1].EventDate.Equal(time.Unix(1584800000, 0)) {
+		t.Errorf("unexpected date %v", events[1].EventDate)
+	}
+}
+
+func TestRiderHistoryErrors(t *testing.T) {
+	c, _ := newFixtureClient(t)
+
+	if _, err := c.RiderHistory(context.Background(), 1111); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v for a missing rider, expected ErrNotFound", err)
+	}
+	if _, err := c.RiderHistory(context.Background(), 2222); !errors.Is(err, ErrMalformed) {
+		t.Errorf("got %v for a broken profile, expected ErrMalformed", err)
+	}
+}
+
+func TestFilterEvents(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2021, 3, d, 18, 0, 0, 0, time.UTC)
+	}
+	events := []Event{
+		{EventTitle: "race 1", EventType: "TYPE_RACE", EventDate: day(1)},
+		{EventTitle: "ride 2", EventType: "TYPE_RIDE", EventDate: day(2)},
+		{EventTitle: "race 3", EventType: "TYPE_RACE TYPE_RACE ", EventDate: day(3)},
+		{EventTitle: "workout 4", EventType: "TYPE_WORKOUT", EventDate: day(4)},
+	}
+
+	cases := []struct {
+		name     string
+		filters  []EventFilter
+		expected []string
+	}{
+		{name: "none", expected: []string{"race 1", "ride 2", "race 3", "workout 4"}},
+		{name: "races", filters: []EventFilter{RacesOnly()}, expected: []string{"race 1", "race 3"}},
+		{name: "rides", filters: []EventFilter{OfType("TYPE_RIDE")}, expected: []string{"ride 2"}},
+		{name: "range", filters: []EventFilter{Between(day(2), day(4))}, expected: []string{"ride 2", "race 3"}},
+		{name: "from", filters: []EventFilter{Between(day(3), time.Time{})}, expected: []string{"race 3", "workout 4"}},
+		{name: "until", filters: []EventFilter{Between(time.Time{}, day(2))}, expected: []string{"race 1"}},
+		{name: "combined", filters: []EventFilter{RacesOnly(), Between(day(2), time.Time{})}, expected: []string{"race 3"}},
+	}
+
+	for _, tc := range cases {
+		got := FilterEvents(events, tc.filters...)
+		if len(got) != len(tc.expected) {
+			t.Errorf("%s: got %d events, expected %v", tc.name, len(got), tc.expected)
+			continue
+		}
+		for i := range got {
+			if got[i].EventTitle != tc.expected[i] {
+				t.Errorf("%s: event %d is %q, expected %q", tc.name, i, got[i].EventTitle, tc.expected[i])
+			}
+		}
+	}
+}
+
+func TestEventStrings(t *testing.T) {
+	c, _ := newFixtureClient(t)
+
+	events, err := c.RiderHistory(context.Background(), 98588)
+	if err != nil {
+		t.Fatalf("RiderHistory: %v", err)
+	}
+
+	headers := EventColumnHeaders(c.Durations())
+	rows := [][]string{headers}
+	for _, e := range events {
+		row := e.Strings(c.Durations())
+		if len(row) != len(headers) {
+			t.Fatalf("row has %d columns, expected %d", len(row), len(headers))
+		}
+		rows = append(rows, row)
+	}
+	checkGolden(t, "history", rows...)
+}
+
+func TestEventStringsDurations(t *testing.T) {
+	c, _ := newFixtureClient(t, WithDurations(Durations(10*time.Minute, 10*time.Second)...))
+
+	events, err := c.RiderHistory(context.Background(), 98588)
+	if err != nil {
+		t.Fatalf("RiderHistory: %v", err)
+	}
+
+	headers := EventColumnHeaders(c.Durations())
+	expected := []string{"Date", "Event", "Type", "AvgWpkg", "FTP", "WFTP", "W10Min", "Wpkg10Min", "W10Sec", "Wpkg10Sec", "Weight"}
+	if strings.Join(headers, ",") != strings.Join(expected, ",") {
+		t.Errorf("got headers %v, expected %v", headers, expected)
+	}
+
+	found := false
+	for _, e := range events {
+		row := e.Strings(c.Durations())
+		if row[6] == "218" && row[8] == "581" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected an event with 218W for 10 minutes and 581W for 10 seconds")
+	}
+}
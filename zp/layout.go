@@ -0,0 +1,258 @@
+package zp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Layout describes the columns of the team spreadsheet. Each rider gets the fixed
+// columns (name, zwid, profile link, categories and weight) followed by a power group
+// for each event set and period, holding the watts and W/kg for each duration.
+type Layout struct {
+	EventSets []EventSet // If there are none, there's just Races
+	Periods   []Period
+	Durations []PowerDuration
+	Sources   SourceStyle // Whether and how to show which event each best value came from
+	BaseURL   string      // The ZwiftPower site that profile and source links go to. DefaultBaseURL if empty.
+	Stats     []Stat      // Statistics to show after the best values in each power group
+	CP        CPModelType // The critical power model to fit to each power group, if any
+	Trend     bool        // Whether to show each rider's trend after the power groups
+	Category  bool        // Whether to show each rider's computed category and upgrade risk
+	Scores    ScoreBands  // If there are any, show each rider's racing score and its category in these bands
+	Weights   bool        // Whether to show the range of each rider's weight and any anomalies in it
+}
+
+func (l Layout) eventSets() []EventSet {
+	if len(l.EventSets) == 0 {
+		return []EventSet{Races}
+	}
+	return l.EventSets
+}
+
+// DefaultLayout is the layout we've always used, with 30, 42, 60 and 90 day windows
+// and durations from 20 minutes down to 5 seconds
+func DefaultLayout() Layout {
+	return Layout{Periods: Windows(DefaultWindows...), Durations: DefaultDurations}
+}
+
+// Headers lists the column headers
+func (l Layout) Headers() []string {
+	output := []string{"Name", "Zwid", "Profile", "Category", "Womens Category", "Weight"}
+	for _, set := range l.eventSets() {
+		for _, p := range l.Periods {
+			output = l.addPowerGroupHeaders(output, set, p)
+		}
+	}
+	if l.Trend {
+		output = append(output, "Trend", "TrendFTP", "TrendWpkg20Min", "TrendRaces")
+	}
+	if l.Category {
+		output = append(output, "ComputedCategory", "NextCategory", "CategoryGap", "UpgradeRisk")
+	}
+	if len(l.Scores) > 0 {
+		output = append(output, "RacingScore", "ScoreCategory")
+	}
+	if l.Weights {
+		output = append(output, "MinWeight", "MaxWeight", "WeightAnomalies", "LatestWeightAnomaly")
+	}
+	return output
+}
+
+// Row turns a rider into a row of the spreadsheet. Periods that the rider has no power
+// group for (because they were imported with different periods) come out as zeros.
+func (l Layout) Row(r RiderDetail) []string {
+	output := []string{
+		r.Name,
+		strconv.Itoa(r.Zwid),
+		siteURL(l.BaseURL) + fmt.Sprintf(zpProfilePath, r.Zwid),
+		catValToString(r.Div),
+		catValToString(r.DivW),
+		strconv.FormatFloat(r.Weight, 'f', 1, 64),
+	}
+	for _, set := range l.eventSets() {
+		for _, p := range l.Periods {
+			powerGroup, _ := r.PowerGroup(set, p)
+			output = l.addPowerGroup(output, powerGroup, r.Weight)
+		}
+	}
+	if l.Trend {
+		// The slopes are per week
+		output = append(output,
+			r.Trend.Direction,
+			strconv.FormatFloat(r.Trend.FTPSlope, 'f', 3, 64),
+			strconv.FormatFloat(r.Trend.Wkg20MinSlope, 'f', 3, 64),
+			strconv.FormatFloat(r.Trend.RacesSlope, 'f', 2, 64),
+		)
+	}
+	if l.Category {
+		gap := ""
+		if r.Category.Next != "" {
+			gap = strconv.FormatFloat(r.Category.Gap, 'f', 2, 64)
+		}
+		output = append(output, r.Category.Category, r.Category.Next, gap, strconv.FormatBool(r.Category.AtRisk))
+	}
+	if len(l.Scores) > 0 {
+		score := ""
+		if r.RacingScore.Present {
+			score = strconv.FormatFloat(r.RacingScore.Value, 'f', 0, 64)
+		}
+		output = append(output, score, l.Scores.Category(r.RacingScore))
+	}
+	if l.Weights {
+		latest := ""
+		if n := len(r.WeightAnomalies); n > 0 {
+			latest = r.WeightAnomalies[n-1].String()
+		}
+		output = append(output,
+			strconv.FormatFloat(r.WeightHistory.Min(), 'f', 1, 64),
+			strconv.FormatFloat(r.WeightHistory.Max(), 'f', 1, 64),
+			strconv.Itoa(len(r.WeightAnomalies)),
+			latest,
+		)
+	}
+	return output
+}
+
+// ColumnHeaders gives the headers for the default layout
+func ColumnHeaders() []string {
+	return DefaultLayout().Headers()
+}
+
+// Strings turns a rider struct into []string, with the power groups the rider was
+// imported with, showing the default durations
+func (r RiderDetail) Strings() []string {
+	output := Layout{}.Row(r)
+	l := Layout{Durations: DefaultDurations}
+	for _, g := range r.PowerGroups {
+		output = l.addPowerGroup(output, g, r.Weight)
+	}
+	return output
+}
+
+func (l Layout) addPowerGroupHeaders(output []string, set EventSet, p Period) []string {
+	baseString := set.Name + p.Label()
+	count := "Races"
+	if set.Name != "" {
+		count = "Events"
+	}
+	output = append(output, fmt.Sprintf("%s%s", count, baseString))
+	output = l.addValueHeader(output, fmt.Sprintf("FTP%s", baseString))
+	for _, d := range l.Durations {
+		output = l.addValueHeader(output, fmt.Sprintf("W%s%s", d.Label(), baseString))
+		output = l.addValueHeader(output, fmt.Sprintf("Wpkg%s%s", d.Label(), baseString))
+	}
+	for _, stat := range l.Stats {
+		output = append(output, fmt.Sprintf("%sFTP%s", stat.Label(), baseString))
+		for _, d := range l.Durations {
+			output = append(output,
+				fmt.Sprintf("%sW%s%s", stat.Label(), d.Label(), baseString),
+				fmt.Sprintf("%sWpkg%s%s", stat.Label(), d.Label(), baseString),
+			)
+		}
+	}
+	if l.CP != NoCP {
+		output = append(output,
+			fmt.Sprintf("CP%s", baseString),
+			fmt.Sprintf("WPrime%s", baseString),
+			fmt.Sprintf("CPWpkg%s", baseString),
+		)
+		if l.CP == CP3Param {
+			output = append(output, fmt.Sprintf("Pmax%s", baseString))
+		}
+		output = append(output, fmt.Sprintf("CPFit%s", baseString))
+	}
+	return output
+}
+
+// addValueHeader adds the header for a best value, and for its source if that has a column
+func (l Layout) addValueHeader(output []string, header string) []string {
+	output = append(output, header)
+	if l.Sources == SourceColumns {
+		output = append(output, header+"Event")
+	}
+	return output
+}
+
+func catValToString(cat int) (catString string) {
+	switch cat {
+	case 5:
+		catString = "A+"
+	case 10:
+		catString = "A"
+	case 20:
+		catString = "B"
+	case 30:
+		catString = "C"
+	case 40:
+		catString = "D"
+	default:
+		catString = ""
+	}
+	return
+}
+
+func (l Layout) addPowerGroup(output []string, powerGroup riderPowerGroup, weight float64) []string {
+	output = append(output, strconv.Itoa(powerGroup.Races))
+	output = l.addValue(output, powerGroup.FTP, strconv.FormatFloat(powerGroup.FTP.Value, 'f', 1, 64))
+	for _, d := range l.Durations {
+		watts, wpkg := powerGroup.Watts[d.Seconds], powerGroup.Wpkg[d.Seconds]
+		output = l.addValue(output, watts, strconv.Itoa(int(watts.Value)))
+		output = l.addValue(output, wpkg, strconv.FormatFloat(wpkg.Value, 'f', 1, 64))
+	}
+	for _, stat := range l.Stats {
+		output = append(output, strconv.FormatFloat(powerGroup.FTPStat(stat), 'f', 1, 64))
+		for _, d := range l.Durations {
+			output = append(output,
+				strconv.Itoa(int(powerGroup.WattsStat(stat, d))),
+				strconv.FormatFloat(powerGroup.WpkgStat(stat, d), 'f', 1, 64),
+			)
+		}
+	}
+	if l.CP != NoCP {
+		output = l.addCP(output, powerGroup, weight)
+	}
+	return output
+}
+
+// addCP adds the critical power model fitted to the group's efforts. If it can't be
+// fitted, the cells are left empty.
+func (l Layout) addCP(output []string, powerGroup riderPowerGroup, weight float64) []string {
+	columns := 4
+	if l.CP == CP3Param {
+		columns = 5
+	}
+
+	fit, err := FitCP(l.CP, powerGroup.Efforts())
+	if err != nil {
+		return append(output, make([]string, columns)...)
+	}
+
+	var cpWpkg float64
+	if weight > 0 {
+		cpWpkg = fit.CP / weight
+	}
+	output = append(output,
+		strconv.Itoa(int(math.Round(fit.CP))),
+		strconv.Itoa(int(math.Round(fit.WPrime))),
+		strconv.FormatFloat(cpWpkg, 'f', 2, 64),
+	)
+	if l.CP == CP3Param {
+		output = append(output, strconv.Itoa(int(math.Round(fit.Pmax))))
+	}
+	return append(output, strconv.FormatFloat(fit.R2, 'f', 2, 64))
+}
+
+// addValue adds a best value, formatted as text, showing where it came from in the layout's style
+func (l Layout) addValue(output []string, best Best, text string) []string {
+	switch l.Sources {
+	case SourceColumns:
+		return append(output, text, best.Event.String())
+	case SourceLinks:
+		if best.Event.ID != "" {
+			// The value stays a number, so the column can still be sorted
+			text = fmt.Sprintf(`=HYPERLINK("%s", %s)`, best.Event.URL(l.BaseURL), text)
+		}
+	}
+	return append(output, text)
+}
@@ -0,0 +1,39 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFeatureColumns checks the columns that each optional feature adds to the layout,
+// for a team as of a fixed date
+func TestFeatureColumns(t *testing.T) {
+	reference := time.Date(2021, 2, 7, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		golden string
+		clubID int
+		opts   []Option
+		layout Layout
+	}{
+		{golden: "trend", clubID: 1234, layout: Layout{Trend: true}},
+		{golden: "categories", clubID: 1234, layout: Layout{Category: true}},
+		{golden: "scores", clubID: 5678, layout: Layout{Scores: DefaultScoreBands}},
+		{golden: "weights", clubID: 5678, opts: []Option{WithWeightBasis(CurrentWeight)}, layout: Layout{Weights: true}},
+	}
+
+	for _, tc := range cases {
+		c, _ := newFixtureClient(t, append([]Option{WithWindows(90), WithReferenceTime(reference)}, tc.opts...)...)
+		riders, err := c.ImportTeam(tc.clubID, 0)
+		if err != nil {
+			t.Fatalf("%s: ImportTeam: %v", tc.golden, err)
+		}
+
+		l := tc.layout
+		l.Periods, l.Durations = Windows(90), Durations(20*time.Minute)
+		rows := [][]string{l.Headers()}
+		for _, r := range riders {
+			rows = append(rows, l.Row(r))
+		}
+		checkGolden(t, tc.golden, rows...)
+	}
+}
@@ -0,0 +1,130 @@
+package zp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period is a span of time that a rider's race power is collected over. Periods are
+// worked out relative to a reference time, which is normally now.
+type Period interface {
+	// Contains reports whether an event at t falls in the period, as of now
+	Contains(t, now time.Time) bool
+	// Label names the period in column headers, e.g. "30Days"
+	Label() string
+}
+
+// Rolling is the last so many days
+type Rolling int
+
+// Contains reports whether t was no more than the period's number of whole days before now
+func (r Rolling) Contains(t, now time.Time) bool {
+	if t.After(now) {
+		return false
+	}
+	daysAgo := int(now.Sub(t).Hours() / 24)
+	return daysAgo <= int(r)
+}
+
+// Label is e.g. "30Days"
+func (r Rolling) Label() string {
+	return fmt.Sprintf("%dDays", int(r))
+}
+
+// Windows turns a list of days into rolling periods. Days that aren't positive are ignored.
+func Windows(days ...int) []Period {
+	var output []Period
+	for _, d := range days {
+		if d > 0 {
+			output = append(output, Rolling(d))
+		}
+	}
+	return output
+}
+
+// CalendarMonth is the calendar month that the reference time is in
+type CalendarMonth struct{}
+
+// Contains reports whether t is in the same calendar month as now, and not after it
+func (CalendarMonth) Contains(t, now time.Time) bool {
+	t = t.In(now.Location())
+	return t.Year() == now.Year() && t.Month() == now.Month() && !t.After(now)
+}
+
+// Label is "Month"
+func (CalendarMonth) Label() string {
+	return "Month"
+}
+
+// YearToDate runs from the start of the reference time's year up to the reference time
+type YearToDate struct{}
+
+// Contains reports whether t is in the same year as now, and not after it
+func (YearToDate) Contains(t, now time.Time) bool {
+	return t.In(now.Location()).Year() == now.Year() && !t.After(now)
+}
+
+// Label is "YTD"
+func (YearToDate) Label() string {
+	return "YTD"
+}
+
+// Season is a named, fixed span of time, such as a league season. It doesn't depend
+// on the reference time.
+type Season struct {
+	Name  string
+	Start time.Time // Inclusive
+	End   time.Time // Exclusive
+}
+
+// Contains reports whether t is within the season
+func (s Season) Contains(t, now time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+// Label is the season's name
+func (s Season) Label() string {
+	return s.Name
+}
+
+// ParsePeriod reads a period written as a number of days ("30"), "month", "ytd", or a
+// season as "name:YYYY-MM-DD:YYYY-MM-DD". A season's dates are in local time, and it
+// includes the whole of its last day.
+func ParsePeriod(s string) (Period, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "month":
+		return CalendarMonth{}, nil
+	case "ytd":
+		return YearToDate{}, nil
+	}
+
+	if days, err := strconv.Atoi(s); err == nil {
+		if days <= 0 {
+			return nil, fmt.Errorf("period %q: days must be positive", s)
+		}
+		return Rolling(days), nil
+	}
+
+	fields := strings.Split(s, ":")
+	if len(fields) != 3 || fields[0] == "" {
+		return nil, fmt.Errorf("period %q: expected days, month, ytd or name:start:end", s)
+	}
+
+	start, err := time.ParseInLocation("2006-01-02", fields[1], time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("period %q: %v", s, err)
+	}
+	last, err := time.ParseInLocation("2006-01-02", fields[2], time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("period %q: %v", s, err)
+	}
+	end := last.AddDate(0, 0, 1)
+	if !end.After(start) {
+		return nil, fmt.Errorf("period %q ends before it starts", s)
+	}
+
+	return Season{Name: fields[0], Start: start, End: end}, nil
+}
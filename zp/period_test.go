@@ -0,0 +1,93 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePeriod(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected Period
+	}{
+		{in: "30", expected: Rolling(30)},
+		{in: " 365 ", expected: Rolling(365)},
+		{in: "month", expected: CalendarMonth{}},
+		{in: "YTD", expected: YearToDate{}},
+		{in: "Spring:2020-03-01:2020-05-31", expected: Season{
+			Name:  "Spring",
+			Start: time.Date(2020, 3, 1, 0, 0, 0, 0, time.Local),
+			End:   time.Date(2020, 6, 1, 0, 0, 0, 0, time.Local),
+		}},
+	}
+	for _, tc := range cases {
+		p, err := ParsePeriod(tc.in)
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if s, ok := p.(Season); ok {
+			e := tc.expected.(Season)
+			if s.Name != e.Name || !s.Start.Equal(e.Start) || !s.End.Equal(e.End) {
+				t.Errorf("%q: got %+v, expected %+v", tc.in, s, e)
+			}
+		} else if p != tc.expected {
+			t.Errorf("%q: got %v, expected %v", tc.in, p, tc.expected)
+		}
+	}
+
+	for _, bad := range []string{"", "0", "-7", "fortnight", "Spring:2020-03-01", "Spring:2020-05-31:2020-03-01", ":2020-03-01:2020-05-31"} {
+		if _, err := ParsePeriod(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestPeriodContains(t *testing.T) {
+	now := time.Date(2021, 3, 15, 12, 0, 0, 0, time.UTC)
+	spring, _ := ParsePeriod("Spring:2021-03-01:2021-05-31")
+
+	cases := []struct {
+		period   Period
+		t        time.Time
+		expected bool
+	}{
+		{Rolling(30), now.AddDate(0, 0, -30), true},
+		{Rolling(30), now.AddDate(0, 0, -31), false},
+		{Rolling(30), now.Add(time.Hour), false},
+		{CalendarMonth{}, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{CalendarMonth{}, time.Date(2021, 2, 28, 23, 59, 0, 0, time.UTC), false},
+		{CalendarMonth{}, time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC), false},
+		{CalendarMonth{}, now.Add(time.Hour), false},
+		{YearToDate{}, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{YearToDate{}, time.Date(2020, 12, 31, 23, 59, 0, 0, time.UTC), false},
+		{YearToDate{}, now.Add(time.Hour), false},
+		{spring, time.Date(2021, 5, 31, 23, 59, 0, 0, time.UTC), true},
+		{spring, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), false},
+		{spring, time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tc := range cases {
+		if got := tc.period.Contains(tc.t, now); got != tc.expected {
+			t.Errorf("%s contains %v: got %v, expected %v", tc.period.Label(), tc.t, got, tc.expected)
+		}
+	}
+}
+
+func TestImportPeriods(t *testing.T) {
+	spring, _ := ParsePeriod("Spring:2020-03-01:2020-05-31")
+	periods := []Period{Rolling(30), CalendarMonth{}, YearToDate{}, spring}
+	reference := time.Date(2020, 3, 31, 0, 0, 0, 0, time.UTC)
+	c, _ := newFixtureClient(t, WithPeriods(periods...), WithReferenceTime(reference))
+
+	riders, err := c.ImportTeam(1234, 0)
+	if err != nil {
+		t.Fatalf("ImportTeam: %v", err)
+	}
+
+	l := Layout{Periods: periods, Durations: DefaultDurations}
+	rows := [][]string{l.Headers()}
+	for _, r := range riders {
+		rows = append(rows, l.Row(r))
+	}
+	checkGolden(t, "periods", rows...)
+}
@@ -0,0 +1,76 @@
+package zp
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// PowerValue is a number from ZwiftPower, such as a power, W/kg or weight. They usually
+// arrive as [value, flag], where value is a number or a numeric string, but ZwiftPower
+// also sends bare numbers and strings, null, empty arrays and empty strings.
+// Present is false if there was no usable value.
+type PowerValue struct {
+	Value   float64
+	Present bool
+}
+
+// UnmarshalJSON accepts every shape of value that ZwiftPower sends. Anything that
+// doesn't hold a number decodes as a PowerValue that isn't Present, rather than an error.
+func (p *PowerValue) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*p = PowerValue{}
+
+	// The value we want is the first element; the second is a flag we don't use
+	if arr, ok := v.([]interface{}); ok {
+		if len(arr) == 0 {
+			return nil
+		}
+		v = arr[0]
+	}
+
+	switch val := v.(type) {
+	case float64:
+		*p = PowerValue{Value: val, Present: true}
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err == nil {
+			*p = PowerValue{Value: f, Present: true}
+		}
+	}
+
+	return nil
+}
+
+// MarshalJSON writes the value as a bare number, or null if it isn't present
+func (p PowerValue) MarshalJSON() ([]byte, error) {
+	if !p.Present {
+		return []byte("null"), nil
+	}
+	return json.Marshal(p.Value)
+}
+
+// ID is an identifier from ZwiftPower. They are usually strings, but sometimes numbers.
+type ID string
+
+// UnmarshalJSON accepts a string or a number
+func (id *ID) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch val := v.(type) {
+	case string:
+		*id = ID(val)
+	case float64:
+		*id = ID(strconv.FormatFloat(val, 'f', -1, 64))
+	default:
+		*id = ""
+	}
+	return nil
+}
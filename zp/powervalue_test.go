@@ -0,0 +1,74 @@
+package zp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPowerValueUnmarshal(t *testing.T) {
+	cases := []struct {
+		json     string
+		expected PowerValue
+	}{
+		{json: `["3.4",0]`, expected: PowerValue{Value: 3.4, Present: true}},
+		{json: `[201,1]`, expected: PowerValue{Value: 201, Present: true}},
+		{json: `["56.3"]`, expected: PowerValue{Value: 56.3, Present: true}},
+		{json: `[" 4.1 ",0]`, expected: PowerValue{Value: 4.1, Present: true}},
+		{json: `210`, expected: PowerValue{Value: 210, Present: true}},
+		{json: `"3.5"`, expected: PowerValue{Value: 3.5, Present: true}},
+		{json: `0`, expected: PowerValue{Value: 0, Present: true}},
+		{json: `null`},
+		{json: `[]`},
+		{json: `""`},
+		{json: `["",1]`},
+		{json: `[null,0]`},
+		{json: `["n/a",0]`},
+		{json: `{"v":1}`},
+		{json: `true`},
+	}
+
+	for _, tc := range cases {
+		// Start from a value that's present to check that absent values reset it
+		p := PowerValue{Value: 99, Present: true}
+		if err := json.Unmarshal([]byte(tc.json), &p); err != nil {
+			t.Errorf("%s: unexpected error %v", tc.json, err)
+			continue
+		}
+		if p != tc.expected {
+			t.Errorf("%s: got %+v, expected %+v", tc.json, p, tc.expected)
+		}
+	}
+}
+
+func TestPowerValueRoundTrip(t *testing.T) {
+	for _, p := range []PowerValue{{Value: 3.4, Present: true}, {}} {
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("marshalling %+v: %v", p, err)
+		}
+
+		var back PowerValue
+		if err := json.Unmarshal(data, &back); err != nil {
+			t.Fatalf("unmarshalling %s: %v", data, err)
+		}
+		if back != p {
+			t.Errorf("%+v came back as %+v", p, back)
+		}
+	}
+}
+
+func TestImportQuirkyRider(t *testing.T) {
+	c, _ := newFixtureClient(t)
+
+	r, err := c.ImportRider(3333)
+	if err != nil {
+		t.Fatalf("ImportRider: %v", err)
+	}
+
+	if r.Rides != 2 || r.Races != 1 {
+		t.Errorf("got %d rides and %d races, expected 2 and 1", r.Rides, r.Races)
+	}
+	if r.LatestRaceWkgFtp != 0 || r.LatestRaceAvgWkg != 0 {
+		t.Errorf("missing values should count as zero, got %.1f and %.1f", r.LatestRaceWkgFtp, r.LatestRaceAvgWkg)
+	}
+}
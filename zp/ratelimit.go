@@ -0,0 +1,137 @@
+package zp
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket shared by every request that a Client makes
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// reserve takes a token from the bucket, and returns how long the caller must wait
+// before using it. Tokens can go negative, so concurrent callers queue up behind each other.
+func (rl *rateLimiter) reserve() time.Duration {
+	if rl == nil {
+		return 0
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	if !rl.last.IsZero() {
+		rl.tokens = math.Min(rl.burst, rl.tokens+now.Sub(rl.last).Seconds()*rl.rate)
+	}
+	rl.last = now
+
+	rl.tokens--
+	if rl.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-rl.tokens / rl.rate * float64(time.Second))
+}
+
+// pause holds back every request until at least d from now, as when ZwiftPower sends
+// Retry-After. It reports false if there's no limiter to hold them back.
+func (rl *rateLimiter) pause(d time.Duration) bool {
+	if rl == nil {
+		return false
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	if !rl.last.IsZero() {
+		rl.tokens = math.Min(rl.burst, rl.tokens+now.Sub(rl.last).Seconds()*rl.rate)
+	}
+	rl.last = now
+
+	// The next reservation leaves the bucket d's worth of tokens short
+	rl.tokens = math.Min(rl.tokens, 1-d.Seconds()*rl.rate)
+	return true
+}
+
+// sleep waits for d, or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// backoff is how long to wait before retry number attempt (counting from 0). It doubles
+// each time up to maxDelay, with jitter so that parallel workers don't retry in lockstep.
+func backoff(attempt int, baseDelay time.Duration, maxDelay time.Duration) time.Duration {
+	d := baseDelay << uint(attempt)
+	if d > maxDelay || d <= 0 {
+		d = maxDelay
+	}
+
+	// Somewhere between half and all of the full delay
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryable reports whether a response with this status code is worth trying again
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// retryAfter interprets a Retry-After header, which is either a number of seconds or an HTTP date
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+
+	if t, err := http.ParseTime(header); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
@@ -0,0 +1,181 @@
+package zp
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RiderSnapshot is what we remember about a rider from one run to the next
+type RiderSnapshot struct {
+	Zwid   int
+	Name   string
+	Div    int
+	DivW   int
+	Weight float64
+	FTP    float64         // The best FTP estimate we've seen, in W/kg
+	Watts  map[int]float64 // The best power we've seen for each duration, keyed by the duration in seconds
+}
+
+// Snapshot is the team as it was after a run
+type Snapshot struct {
+	Taken  time.Time
+	Riders []RiderSnapshot
+}
+
+// snapshotOf records the rider's details and their best values across all their power groups
+func snapshotOf(r RiderDetail) RiderSnapshot {
+	s := RiderSnapshot{Zwid: r.Zwid, Name: r.Name, Div: r.Div, DivW: r.DivW, Weight: r.Weight, Watts: map[int]float64{}}
+	for _, g := range r.PowerGroups {
+		s.FTP = math.Max(s.FTP, g.FTP.Value)
+		for seconds, best := range g.Watts {
+			if best.Value > s.Watts[seconds] {
+				s.Watts[seconds] = best.Value
+			}
+		}
+	}
+	return s
+}
+
+func (s Snapshot) rider(zwid int) (RiderSnapshot, bool) {
+	for _, r := range s.Riders {
+		if r.Zwid == zwid {
+			return r, true
+		}
+	}
+	return RiderSnapshot{}, false
+}
+
+// These are the kinds of change to a team's roster
+const (
+	Joined                = "Joined"
+	Left                  = "Left"
+	CategoryChanged       = "Category"
+	WomensCategoryChanged = "WomensCategory"
+	WeightChanged         = "Weight"
+	NewPB                 = "PB"
+)
+
+// RosterChange is something that changed about a team between two runs
+type RosterChange struct {
+	Zwid   int
+	Name   string
+	Change string // Joined, Left, CategoryChanged, WomensCategoryChanged, WeightChanged or NewPB
+	What   string // For a PB, what it's for, e.g. "FTP" or "W20Min"
+	Before string
+	After  string
+}
+
+// RosterChangeHeaders are the headers for the rows from RosterChange.Strings
+func RosterChangeHeaders() []string {
+	return []string{"Name", "Zwid", "Change", "What", "Before", "After"}
+}
+
+// Strings turns a change into []string, matching RosterChangeHeaders
+func (c RosterChange) Strings() []string {
+	return []string{c.Name, strconv.Itoa(c.Zwid), c.Change, c.What, c.Before, c.After}
+}
+
+// RosterCheck decides which changes between runs to report
+type RosterCheck struct {
+	Weight float64 // Weight changes of at least this many kg are reported
+}
+
+// DefaultRosterCheck reports weight changes of 2kg or more
+var DefaultRosterCheck = RosterCheck{Weight: 2}
+
+// Update compares the riders with the previous snapshot, and returns a new snapshot of
+// them along with what changed. Riders in missing, who couldn't be imported this time,
+// are carried over from the previous snapshot rather than counted as leaving. The new
+// snapshot keeps each rider's best values from before if they're higher, so that PBs
+// are only reported once. If there isn't a previous snapshot, nothing has changed.
+func (c RosterCheck) Update(previous Snapshot, riders []RiderDetail, missing []int, taken time.Time) (Snapshot, []RosterChange) {
+	current := Snapshot{Taken: taken}
+	var changes []RosterChange
+	first := previous.Taken.IsZero() && len(previous.Riders) == 0
+
+	for _, r := range riders {
+		now := snapshotOf(r)
+		before, ok := previous.rider(r.Zwid)
+		if !ok {
+			if !first {
+				changes = append(changes, RosterChange{Zwid: r.Zwid, Name: r.Name, Change: Joined, After: catValToString(r.Div)})
+			}
+			current.Riders = append(current.Riders, now)
+			continue
+		}
+
+		changes = append(changes, c.compare(before, now)...)
+		current.Riders = append(current.Riders, keepBests(before, now))
+	}
+
+	for _, before := range previous.Riders {
+		if _, ok := current.rider(before.Zwid); ok {
+			continue
+		}
+		if containsInt(missing, before.Zwid) {
+			current.Riders = append(current.Riders, before)
+			continue
+		}
+		changes = append(changes, RosterChange{Zwid: before.Zwid, Name: before.Name, Change: Left, Before: catValToString(before.Div)})
+	}
+
+	return current, changes
+}
+
+// compare finds what changed about a rider who's in both snapshots
+func (c RosterCheck) compare(before, now RiderSnapshot) []RosterChange {
+	var changes []RosterChange
+	change := func(kind, what, b, a string) {
+		changes = append(changes, RosterChange{Zwid: now.Zwid, Name: now.Name, Change: kind, What: what, Before: b, After: a})
+	}
+
+	if before.Div != now.Div {
+		change(CategoryChanged, "", catValToString(before.Div), catValToString(now.Div))
+	}
+	if before.DivW != now.DivW {
+		change(WomensCategoryChanged, "", catValToString(before.DivW), catValToString(now.DivW))
+	}
+	if before.Weight > 0 && now.Weight > 0 && c.Weight > 0 && math.Abs(now.Weight-before.Weight) >= c.Weight {
+		change(WeightChanged, "", strconv.FormatFloat(before.Weight, 'f', 1, 64), strconv.FormatFloat(now.Weight, 'f', 1, 64))
+	}
+
+	if before.FTP > 0 && now.FTP > before.FTP {
+		change(NewPB, "FTP", strconv.FormatFloat(before.FTP, 'f', 1, 64), strconv.FormatFloat(now.FTP, 'f', 1, 64))
+	}
+	seconds := make([]int, 0, len(now.Watts))
+	for s := range now.Watts {
+		seconds = append(seconds, s)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(seconds)))
+	for _, s := range seconds {
+		if before.Watts[s] > 0 && now.Watts[s] > before.Watts[s] {
+			change(NewPB, "W"+Duration(s).Label(), strconv.FormatFloat(before.Watts[s], 'f', 0, 64), strconv.FormatFloat(now.Watts[s], 'f', 0, 64))
+		}
+	}
+	return changes
+}
+
+// keepBests is now, with any best values from before that were higher
+func keepBests(before, now RiderSnapshot) RiderSnapshot {
+	now.FTP = math.Max(now.FTP, before.FTP)
+	watts := map[int]float64{}
+	for s, w := range before.Watts {
+		watts[s] = w
+	}
+	for s, w := range now.Watts {
+		watts[s] = math.Max(watts[s], w)
+	}
+	now.Watts = watts
+	return now
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
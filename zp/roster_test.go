@@ -0,0 +1,103 @@
+package zp
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func rosterRider(zwid int, name string, div, divW int, weight, ftp, w20 float64) RiderDetail {
+	return RiderDetail{
+		Zwid:   zwid,
+		Name:   name,
+		Div:    div,
+		DivW:   divW,
+		Weight: weight,
+		PowerGroups: []riderPowerGroup{
+			{Period: Rolling(30), FTP: Best{Value: ftp}, Watts: riderPower{1200: {Value: w20}, 60: {Value: 400}}},
+			{Period: Rolling(90), FTP: Best{Value: ftp - 0.1}, Watts: riderPower{1200: {Value: w20 - 5}, 60: {Value: 450}}},
+		},
+	}
+}
+
+func changeRows(changes []RosterChange) []string {
+	var rows []string
+	for _, c := range changes {
+		rows = append(rows, strings.Join(c.Strings(), ","))
+	}
+	return rows
+}
+
+func TestRosterUpdate(t *testing.T) {
+	first := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 7)
+
+	// The first run has nothing to compare with
+	previous, changes := DefaultRosterCheck.Update(Snapshot{}, []RiderDetail{
+		rosterRider(1, "Ann", 30, 30, 60, 3.1, 200),
+		rosterRider(2, "Bob", 20, 0, 80, 3.5, 290),
+		rosterRider(3, "Cat", 20, 0, 70, 3.4, 250),
+		rosterRider(4, "Dan", 40, 0, 90, 2.2, 210),
+	}, nil, first)
+	if len(changes) != 0 {
+		t.Errorf("expected no changes on the first run, got %v", changeRows(changes))
+	}
+	if len(previous.Riders) != 4 || previous.Riders[0].Watts[60] != 450 || previous.Riders[0].FTP != 3.1 {
+		t.Fatalf("got snapshot %+v", previous)
+	}
+
+	current, changes := DefaultRosterCheck.Update(previous, []RiderDetail{
+		rosterRider(1, "Ann", 20, 20, 60, 3.3, 210),  // Upgraded, with new PBs
+		rosterRider(2, "Bob", 20, 0, 77.5, 3.4, 280), // Lost weight, but no PBs
+		rosterRider(5, "Eve", 30, 30, 55, 2.8, 160),  // New
+	}, []int{4}, second) // Dan couldn't be imported, and Cat has left
+
+	expected := []string{
+		"Ann,1,Category,,C,B",
+		"Ann,1,WomensCategory,,C,B",
+		"Ann,1,PB,FTP,3.1,3.3",
+		"Ann,1,PB,W20Min,200,210",
+		"Bob,2,Weight,,80.0,77.5",
+		"Eve,5,Joined,,,C",
+		"Cat,3,Left,,B,",
+	}
+	if got := changeRows(changes); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got changes\n%s\nexpected\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+
+	var zwids []int
+	for _, r := range current.Riders {
+		zwids = append(zwids, r.Zwid)
+	}
+	if !reflect.DeepEqual(zwids, []int{1, 2, 5, 4}) || !current.Taken.Equal(second) {
+		t.Errorf("got riders %v in snapshot taken %v", zwids, current.Taken)
+	}
+
+	// Bob's bests from before are kept, so that he doesn't get PBs for getting back to them
+	bob := current.Riders[1]
+	if bob.FTP != 3.5 || bob.Watts[1200] != 290 || bob.Weight != 77.5 {
+		t.Errorf("got %+v for Bob", bob)
+	}
+	_, changes = DefaultRosterCheck.Update(current, []RiderDetail{rosterRider(2, "Bob", 20, 0, 77.5, 3.45, 285)}, []int{1, 4, 5}, second.AddDate(0, 0, 7))
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changeRows(changes))
+	}
+}
+
+func TestSnapshotJSON(t *testing.T) {
+	s, _ := DefaultRosterCheck.Update(Snapshot{}, []RiderDetail{rosterRider(1, "Ann", 30, 30, 60, 3.1, 200)}, nil, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got Snapshot
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("got %+v, expected %+v", got, s)
+	}
+}
@@ -0,0 +1,93 @@
+package zp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScoreBand is the lowest racing score in a category
+type ScoreBand struct {
+	Category string
+	Min      float64
+}
+
+// ScoreBands map racing scores to categories. They don't need to be in order.
+type ScoreBands []ScoreBand
+
+// DefaultScoreBands are Zwift's racing score categories
+var DefaultScoreBands = ScoreBands{
+	{Category: "A", Min: 690},
+	{Category: "B", Min: 520},
+	{Category: "C", Min: 350},
+	{Category: "D", Min: 180},
+	{Category: "E", Min: 0},
+}
+
+// Category is the category of the highest band that score reaches, or "" if there's no
+// score or it's below all the bands
+func (b ScoreBands) Category(score PowerValue) string {
+	if !score.Present {
+		return ""
+	}
+	category, min := "", 0.0
+	for _, band := range b {
+		if score.Value >= band.Min && (category == "" || band.Min > min) {
+			category, min = band.Category, band.Min
+		}
+	}
+	return category
+}
+
+// Filter keeps the riders whose racing score puts them in one of the categories, in
+// their original order. Riders without a score are left out.
+func (b ScoreBands) Filter(riders []RiderDetail, categories ...string) []RiderDetail {
+	wanted := map[string]bool{}
+	for _, c := range categories {
+		wanted[strings.ToUpper(strings.TrimSpace(c))] = true
+	}
+
+	var output []RiderDetail
+	for _, r := range riders {
+		if wanted[strings.ToUpper(b.Category(r.RacingScore))] {
+			output = append(output, r)
+		}
+	}
+	return output
+}
+
+// String writes the bands the way ParseScoreBands reads them, highest first
+func (b ScoreBands) String() string {
+	sorted := append(ScoreBands(nil), b...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Min > sorted[j].Min })
+	parts := make([]string, len(sorted))
+	for i, band := range sorted {
+		parts[i] = band.Category + ":" + strconv.FormatFloat(band.Min, 'f', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseScoreBands reads bands written as category:score, e.g. "A:690,B:520,C:350,D:180,E:0"
+func ParseScoreBands(s string) (ScoreBands, error) {
+	var bands ScoreBands
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Split(part, ":")
+		if len(fields) != 2 || strings.TrimSpace(fields[0]) == "" {
+			return nil, fmt.Errorf("score band %q: expected category:score, e.g. A:690", part)
+		}
+		min, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("score band %q: %v", part, err)
+		}
+		bands = append(bands, ScoreBand{Category: strings.TrimSpace(fields[0]), Min: min})
+	}
+	if len(bands) == 0 {
+		return nil, fmt.Errorf("no score bands in %q", s)
+	}
+	return bands, nil
+}
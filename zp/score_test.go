@@ -0,0 +1,88 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScoreBandsCategory(t *testing.T) {
+	bands := ScoreBands{{"C", 350}, {"A", 690}, {"B", 520}} // Out of order, and nothing below 350
+	cases := []struct {
+		score    PowerValue
+		expected string
+	}{
+		{PowerValue{Value: 700, Present: true}, "A"},
+		{PowerValue{Value: 690, Present: true}, "A"},
+		{PowerValue{Value: 689.9, Present: true}, "B"},
+		{PowerValue{Value: 350, Present: true}, "C"},
+		{PowerValue{Value: 349, Present: true}, ""},
+		{PowerValue{}, ""},
+	}
+	for _, tc := range cases {
+		if got := bands.Category(tc.score); got != tc.expected {
+			t.Errorf("%+v: got %q, expected %q", tc.score, got, tc.expected)
+		}
+	}
+
+	if got := DefaultScoreBands.Category(PowerValue{Value: 0, Present: true}); got != "E" {
+		t.Errorf("a score of 0 is in %q, expected E", got)
+	}
+}
+
+func TestParseScoreBands(t *testing.T) {
+	bands, err := ParseScoreBands(" B:520, A:690 ,C:350.5")
+	if err != nil {
+		t.Fatalf("ParseScoreBands: %v", err)
+	}
+	if got := bands.String(); got != "A:690,B:520,C:350.5" {
+		t.Errorf("got %q", got)
+	}
+
+	if got := DefaultScoreBands.String(); got != "A:690,B:520,C:350,D:180,E:0" {
+		t.Errorf("default bands are %q", got)
+	}
+
+	for _, s := range []string{"", "A", "A:lots", ":500", "A:1:2"} {
+		if _, err := ParseScoreBands(s); err == nil {
+			t.Errorf("expected an error from %q", s)
+		}
+	}
+}
+
+func TestScoreBandsFilter(t *testing.T) {
+	riders := []RiderDetail{
+		{Name: "a", RacingScore: PowerValue{Value: 700, Present: true}},
+		{Name: "b", RacingScore: PowerValue{Value: 600, Present: true}},
+		{Name: "none"},
+		{Name: "c", RacingScore: PowerValue{Value: 400, Present: true}},
+	}
+
+	got := DefaultScoreBands.Filter(riders, "c", "A ")
+	if len(got) != 2 || got[0].Name != "a" || got[1].Name != "c" {
+		t.Errorf("got %v", got)
+	}
+	if got := DefaultScoreBands.Filter(riders); len(got) != 0 {
+		t.Errorf("expected no riders without any categories, got %v", got)
+	}
+}
+
+func TestRacingScoreFromEvents(t *testing.T) {
+	cases := []struct {
+		asOf     time.Time
+		expected PowerValue
+	}{
+		{time.Date(2021, 2, 7, 0, 0, 0, 0, time.UTC), PowerValue{Value: 436.8, Present: true}},
+		{time.Date(2021, 1, 20, 0, 0, 0, 0, time.UTC), PowerValue{Value: 412, Present: true}},
+		{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), PowerValue{}},
+	}
+	for _, tc := range cases {
+		c, _ := newFixtureClient(t, WithReferenceTime(tc.asOf))
+		r, err := c.ImportRider(5555)
+		if err != nil {
+			t.Fatalf("ImportRider: %v", err)
+		}
+		if r.RacingScore != tc.expected {
+			t.Errorf("as of %v: got %+v, expected %+v", tc.asOf, r.RacingScore, tc.expected)
+		}
+	}
+}
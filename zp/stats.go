@@ -0,0 +1,138 @@
+package zp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Stat summarises all of a rider's values for something in a power group, e.g. their
+// median 5 minute power
+type Stat interface {
+	// Of works out the statistic. There's always at least one value.
+	Of(values []float64) float64
+	// Label goes in front of the column headers, e.g. "Median"
+	Label() string
+}
+
+// Mean is the average value
+type Mean struct{}
+
+// Of is the mean of the values
+func (Mean) Of(values []float64) float64 {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// Label is "Mean"
+func (Mean) Label() string {
+	return "Mean"
+}
+
+// Median is the middle value
+type Median struct{}
+
+// Of is the median of the values
+func (Median) Of(values []float64) float64 {
+	sorted := sortedDescending(values)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// Label is "Median"
+func (Median) Label() string {
+	return "Median"
+}
+
+// NthBest is the value that ranks this far from the top, e.g. NthBest(3) is the third best
+type NthBest int
+
+// Of is the nth best of the values, or 0 if there aren't that many
+func (n NthBest) Of(values []float64) float64 {
+	if int(n) < 1 || int(n) > len(values) {
+		return 0
+	}
+	return sortedDescending(values)[n-1]
+}
+
+// Label is e.g. "3rdBest"
+func (n NthBest) Label() string {
+	return ordinal(int(n)) + "Best"
+}
+
+func sortedDescending(values []float64) []float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+	return sorted
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// ParseStat reads "mean", "median", or an nth best written as e.g. "3rd" or "3"
+func ParseStat(s string) (Stat, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "mean":
+		return Mean{}, nil
+	case "median":
+		return Median{}, nil
+	}
+
+	digits := strings.TrimRight(s, "stndrh")
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 1 || (digits != s && ordinal(n) != s) {
+		return nil, fmt.Errorf("unknown statistic %q: expected mean, median, or e.g. 3rd for the third best", s)
+	}
+	return NthBest(n), nil
+}
+
+// efforts holds every value for each duration, keyed by the duration in seconds
+type efforts map[int][]float64
+
+func (e efforts) add(d PowerDuration, v PowerValue) {
+	if v.Present && v.Value > 0 {
+		e[d.Seconds] = append(e[d.Seconds], v.Value)
+	}
+}
+
+// statOf works out stat for values, or 0 if there aren't any
+func statOf(stat Stat, values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return stat.Of(values)
+}
+
+// FTPStat works out stat across the FTP estimates from each event in the group
+func (g riderPowerGroup) FTPStat(stat Stat) float64 {
+	return statOf(stat, g.FTPs)
+}
+
+// WattsStat works out stat across each event's best power for duration d
+func (g riderPowerGroup) WattsStat(stat Stat, d PowerDuration) float64 {
+	return statOf(stat, g.WattsEfforts[d.Seconds])
+}
+
+// WpkgStat works out stat across each event's best W/kg for duration d
+func (g riderPowerGroup) WpkgStat(stat Stat, d PowerDuration) float64 {
+	return statOf(stat, g.WpkgEfforts[d.Seconds])
+}
@@ -0,0 +1,81 @@
+package zp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	values := []float64{3.1, 4.2, 2.9, 3.6}
+	cases := []struct {
+		stat     Stat
+		label    string
+		expected float64
+	}{
+		{Mean{}, "Mean", 3.45},
+		{Median{}, "Median", 3.35},
+		{NthBest(1), "1stBest", 4.2},
+		{NthBest(2), "2ndBest", 3.6},
+		{NthBest(3), "3rdBest", 3.1},
+		{NthBest(5), "5thBest", 0},
+	}
+	for _, tc := range cases {
+		if got := tc.stat.Of(values); math.Abs(got-tc.expected) > 1e-9 {
+			t.Errorf("%s: got %v, expected %v", tc.label, got, tc.expected)
+		}
+		if tc.stat.Label() != tc.label {
+			t.Errorf("got label %q, expected %q", tc.stat.Label(), tc.label)
+		}
+	}
+
+	if m := (Median{}).Of([]float64{5, 1, 3}); m != 3 {
+		t.Errorf("got median %v of an odd number of values, expected 3", m)
+	}
+	if values[0] != 3.1 {
+		t.Errorf("working out statistics reordered the values: %v", values)
+	}
+	if NthBest(11).Label() != "11thBest" || NthBest(22).Label() != "22ndBest" {
+		t.Errorf("unexpected labels %q and %q", NthBest(11).Label(), NthBest(22).Label())
+	}
+}
+
+func TestParseStat(t *testing.T) {
+	for in, expected := range map[string]Stat{"mean": Mean{}, "Median": Median{}, "3rd": NthBest(3), "2": NthBest(2), "11th": NthBest(11)} {
+		if s, err := ParseStat(in); err != nil || s != expected {
+			t.Errorf("%q: got %v, %v, expected %v", in, s, err, expected)
+		}
+	}
+	for _, bad := range []string{"", "mode", "0", "3th", "best"} {
+		if _, err := ParseStat(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestImportStats(t *testing.T) {
+	c, _ := newFixtureClient(t, WithWindows(365), WithReferenceTime(time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC)))
+
+	r, err := c.ImportRider(1261784)
+	if err != nil {
+		t.Fatalf("ImportRider: %v", err)
+	}
+
+	g, _ := r.PowerGroup(Races, Rolling(365))
+	if len(g.FTPs) != 13 {
+		t.Fatalf("got %d FTP values, expected 13", len(g.FTPs))
+	}
+	if median := g.FTPStat(Median{}); median != 2.6 {
+		t.Errorf("got median FTP %v, expected 2.6", median)
+	}
+	if third := g.WattsStat(NthBest(3), Duration(1200)); third != 161 {
+		t.Errorf("got third best 20 minute power %v, expected 161", third)
+	}
+
+	l := Layout{Periods: Windows(365), Durations: Durations(20*time.Minute, 5*time.Minute), Stats: []Stat{Mean{}, Median{}, NthBest(3)}}
+	headers, row := l.Headers(), l.Row(r)
+	if len(headers) != len(row) {
+		t.Errorf("%d headers for %d values", len(headers), len(row))
+	}
+	checkGolden(t, "stats", headers, row)
+}
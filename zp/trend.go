@@ -0,0 +1,147 @@
+package zp
+
+import "time"
+
+// Trend directions
+const (
+	TrendUp   = "up"
+	TrendFlat = "flat"
+	TrendDown = "down"
+)
+
+// Week summarises a rider's events in one week
+type Week struct {
+	Start    time.Time // Midnight at the start of Monday
+	Events   int
+	Races    int
+	Wkg20Min float64 // The best 20 minute W/kg, or 0 if there wasn't one
+	FTP      float64 // The best FTP estimate in W/kg, or 0 if there wasn't one
+}
+
+// Trend is how a rider's form has been changing, week by week
+type Trend struct {
+	Weeks []Week // Oldest first
+
+	// Slopes of the least squares lines through the weekly values, per week. Weeks
+	// without a power value are left out of the power slopes.
+	FTPSlope      float64
+	Wkg20MinSlope float64
+	RacesSlope    float64
+
+	// Direction is TrendUp, TrendFlat or TrendDown, going by the FTP slope, or "" if
+	// there weren't enough weeks with an FTP estimate to tell
+	Direction string
+}
+
+// TrendAnalyser works out riders' trends
+type TrendAnalyser struct {
+	Weeks    int        // How many weeks to look back over, including the current one
+	Flat     float64    // FTP slopes, in W/kg per week, smaller than this count as flat
+	Classify Classifier // Picks out the races counted in each week
+}
+
+// DefaultTrendAnalyser looks at the last 12 weeks, and calls a change of less than
+// 0.01 W/kg per week flat
+var DefaultTrendAnalyser = TrendAnalyser{Weeks: 12, Flat: 0.01}
+
+// Analyse works out the trend in events over the weeks up to now
+func (a TrendAnalyser) Analyse(events []Event, now time.Time) Trend {
+	classify := a.Classify.orDefault()
+
+	var trend Trend
+	if a.Weeks < 1 {
+		return trend
+	}
+
+	first := startOfWeek(now).AddDate(0, 0, -7*(a.Weeks-1))
+	trend.Weeks = make([]Week, a.Weeks)
+	for i := range trend.Weeks {
+		trend.Weeks[i].Start = first.AddDate(0, 0, 7*i)
+	}
+
+	for _, e := range events {
+		if e.EventDate.Before(first) || e.EventDate.After(now) {
+			continue
+		}
+
+		// Count calendar days rather than hours, so that clock changes don't matter
+		i := daysBetween(first, e.EventDate) / 7
+		if i < 0 || i >= len(trend.Weeks) {
+			continue
+		}
+
+		w := &trend.Weeks[i]
+		w.Events++
+		if Races.Includes(classify(e)) {
+			w.Races++
+		}
+		if e.Wkg20min.Value > w.Wkg20Min {
+			w.Wkg20Min = e.Wkg20min.Value
+		}
+		if e.WkgFtp.Value > w.FTP {
+			w.FTP = e.WkgFtp.Value
+		}
+	}
+
+	var ftpX, ftpY, wkgX, wkgY, raceX, raceY []float64
+	for i, w := range trend.Weeks {
+		x := float64(i)
+		if w.FTP > 0 {
+			ftpX, ftpY = append(ftpX, x), append(ftpY, w.FTP)
+		}
+		if w.Wkg20Min > 0 {
+			wkgX, wkgY = append(wkgX, x), append(wkgY, w.Wkg20Min)
+		}
+		raceX, raceY = append(raceX, x), append(raceY, float64(w.Races))
+	}
+
+	var ok bool
+	trend.FTPSlope, ok = slope(ftpX, ftpY)
+	trend.Wkg20MinSlope, _ = slope(wkgX, wkgY)
+	trend.RacesSlope, _ = slope(raceX, raceY)
+
+	if ok {
+		switch {
+		case trend.FTPSlope >= a.Flat:
+			trend.Direction = TrendUp
+		case trend.FTPSlope <= -a.Flat:
+			trend.Direction = TrendDown
+		default:
+			trend.Direction = TrendFlat
+		}
+	}
+	return trend
+}
+
+// startOfWeek is midnight at the start of the Monday on or before t, in t's location
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween counts the calendar days from the day of from to the day of to, in from's location
+func daysBetween(from, to time.Time) int {
+	to = to.In(from.Location())
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// slope is the gradient of the least squares line through the points. It's not ok if
+// there are fewer than two distinct xs.
+func slope(xs, ys []float64) (float64, bool) {
+	n := float64(len(xs))
+	var sumX, sumY, sumXX, sumXY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+		sumXX += xs[i] * xs[i]
+		sumXY += xs[i] * ys[i]
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if len(xs) < 2 || denominator == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
@@ -0,0 +1,82 @@
+package zp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// weeklyEvents makes a race every Wednesday for len(ftps) weeks up to the week of now
+func weeklyEvents(now time.Time, ftps ...float64) []Event {
+	firstWednesday := startOfWeek(now).AddDate(0, 0, 2-7*(len(ftps)-1))
+	events := make([]Event, len(ftps))
+	for i, ftp := range ftps {
+		events[i] = Event{
+			EventType: "TYPE_RACE",
+			EventDate: firstWednesday.AddDate(0, 0, 7*i),
+			WkgFtp:    PowerValue{Value: ftp, Present: true},
+			Wkg20min:  PowerValue{Value: ftp + 0.2, Present: true},
+		}
+	}
+	return events
+}
+
+func TestTrendAnalyse(t *testing.T) {
+	now := time.Date(2021, 3, 12, 18, 0, 0, 0, time.UTC) // A Friday
+	a := TrendAnalyser{Weeks: 4, Flat: 0.01}
+
+	cases := []struct {
+		name      string
+		events    []Event
+		slope     float64
+		direction string
+	}{
+		{"improving", weeklyEvents(now, 3.0, 3.1, 3.2, 3.3), 0.1, TrendUp},
+		{"detraining", weeklyEvents(now, 3.3, 3.2, 3.1, 3.0), -0.1, TrendDown},
+		{"steady", weeklyEvents(now, 3.0, 3.01, 3.0, 3.005), 0.0005, TrendFlat},
+		{"one week", weeklyEvents(now, 3.0), 0, ""},
+		{"nothing", nil, 0, ""},
+	}
+	for _, tc := range cases {
+		trend := a.Analyse(tc.events, now)
+		if math.Abs(trend.FTPSlope-tc.slope) > 1e-9 || trend.Direction != tc.direction {
+			t.Errorf("%s: got slope %v and direction %q, expected %v and %q", tc.name, trend.FTPSlope, trend.Direction, tc.slope, tc.direction)
+		}
+		if math.Abs(trend.Wkg20MinSlope-tc.slope) > 1e-9 {
+			t.Errorf("%s: got 20 minute slope %v, expected %v", tc.name, trend.Wkg20MinSlope, tc.slope)
+		}
+	}
+}
+
+func TestTrendWeeks(t *testing.T) {
+	now := time.Date(2021, 3, 12, 18, 0, 0, 0, time.UTC) // A Friday
+	events := []Event{
+		{EventType: "TYPE_RACE", EventDate: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},  // The Monday two weeks ago
+		{EventType: "TYPE_RIDE", EventDate: time.Date(2021, 3, 7, 23, 0, 0, 0, time.UTC)}, // Last Sunday
+		{EventType: "TYPE_RACE", EventDate: time.Date(2021, 3, 8, 6, 0, 0, 0, time.UTC)},  // This Monday
+		{EventType: "TYPE_RACE", EventDate: time.Date(2021, 3, 13, 9, 0, 0, 0, time.UTC)}, // Tomorrow
+		{EventType: "TYPE_RACE", EventDate: time.Date(2021, 2, 21, 9, 0, 0, 0, time.UTC)}, // Too long ago
+	}
+
+	trend := TrendAnalyser{Weeks: 3}.Analyse(events, now)
+	if len(trend.Weeks) != 3 {
+		t.Fatalf("got %d weeks, expected 3", len(trend.Weeks))
+	}
+	expected := []struct {
+		start         string
+		events, races int
+	}{
+		{"2021-02-22", 0, 0},
+		{"2021-03-01", 2, 1},
+		{"2021-03-08", 1, 1},
+	}
+	for i, e := range expected {
+		w := trend.Weeks[i]
+		if w.Start.Format("2006-01-02") != e.start || w.Events != e.events || w.Races != e.races {
+			t.Errorf("week %d: got %+v, expected %+v", i, w, e)
+		}
+	}
+	if math.Abs(trend.RacesSlope-0.5) > 1e-9 {
+		t.Errorf("got races slope %v, expected 0.5", trend.RacesSlope)
+	}
+}
@@ -0,0 +1,190 @@
+package zp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WeightPoint is the weight a rider had in an event
+type WeightPoint struct {
+	Weight float64
+	Race   bool
+	Event  EventRef
+}
+
+// WeightHistory is a rider's weight in each of their events, oldest first
+type WeightHistory []WeightPoint
+
+// weightHistory collects the weights recorded with the events up to now. Events without
+// a date or a weight are left out.
+func weightHistory(events []Event, classify Classifier, now time.Time) WeightHistory {
+	var h WeightHistory
+	for _, e := range events {
+		if e.EventDate.IsZero() || e.EventDate.Unix() == 0 || e.EventDate.After(now) || !e.Weight.Present || e.Weight.Value <= 0 {
+			continue
+		}
+		h = append(h, WeightPoint{Weight: e.Weight.Value, Race: Races.Includes(classify(e)), Event: e.Ref()})
+	}
+	sort.SliceStable(h, func(i, j int) bool { return h[i].Event.Date.Before(h[j].Event.Date) })
+	return h
+}
+
+// Latest is the most recent weight, or 0 if there isn't one
+func (h WeightHistory) Latest() float64 {
+	if len(h) == 0 {
+		return 0
+	}
+	return h[len(h)-1].Weight
+}
+
+// Min is the lowest weight, or 0 if there isn't one
+func (h WeightHistory) Min() float64 {
+	var min float64
+	for i, p := range h {
+		if i == 0 || p.Weight < min {
+			min = p.Weight
+		}
+	}
+	return min
+}
+
+// Max is the highest weight, or 0 if there isn't one
+func (h WeightHistory) Max() float64 {
+	var max float64
+	for _, p := range h {
+		if p.Weight > max {
+			max = p.Weight
+		}
+	}
+	return max
+}
+
+// Median is the middle weight, or 0 if there isn't one
+func (h WeightHistory) Median() float64 {
+	weights := make([]float64, len(h))
+	for i, p := range h {
+		weights[i] = p.Weight
+	}
+	return statOf(Median{}, weights)
+}
+
+// WeightAnomaly is a race where the rider was suddenly lighter than they'd been shortly
+// before it
+type WeightAnomaly struct {
+	Before WeightPoint // The heaviest the rider was in the days before the race
+	Race   WeightPoint
+}
+
+// Drop is how much lighter the rider was for the race, in kg
+func (a WeightAnomaly) Drop() float64 {
+	return a.Before.Weight - a.Race.Weight
+}
+
+// String describes the anomaly, e.g. "-2.3kg in Crit City Race (2020-12-02)"
+func (a WeightAnomaly) String() string {
+	return fmt.Sprintf("-%.1fkg in %s", a.Drop(), a.Race.Event)
+}
+
+// WeightCheck decides which weight changes are anomalies
+type WeightCheck struct {
+	Drop float64 // A race weight at least this many kg lighter than before is an anomaly
+	Days int     // How many days before each race to compare with
+}
+
+// DefaultWeightCheck flags races where the rider was 2kg or more lighter than in any
+// event in the previous two weeks
+var DefaultWeightCheck = WeightCheck{Drop: 2, Days: 14}
+
+// Anomalies finds the races in the history where the rider was suddenly lighter, oldest first
+func (c WeightCheck) Anomalies(h WeightHistory) []WeightAnomaly {
+	var output []WeightAnomaly
+	if c.Drop <= 0 {
+		return output
+	}
+	for i, p := range h {
+		if !p.Race {
+			continue
+		}
+		from := p.Event.Date.AddDate(0, 0, -c.Days)
+		var before WeightPoint
+		for _, q := range h[:i] {
+			if !q.Event.Date.Before(from) && q.Weight > before.Weight {
+				before = q
+			}
+		}
+		if before.Weight-p.Weight >= c.Drop {
+			output = append(output, WeightAnomaly{Before: before, Race: p})
+		}
+	}
+	return output
+}
+
+// WeightBasis is the weight that W/kg values in power groups are worked out from
+type WeightBasis int
+
+// These are the weights that W/kg can be worked out from
+const (
+	ReportedWeight WeightBasis = iota // Use the W/kg that ZwiftPower reports
+	CurrentWeight                     // The rider's current weight, from the team list or else their latest event
+	EventWeight                       // The rider's weight in each event
+	MedianWeight                      // The median of the rider's weights in their events
+)
+
+// weightFor is the weight to divide the event's watts by, or 0 to use the reported W/kg
+func (b WeightBasis) weightFor(e Event, current float64, h WeightHistory) float64 {
+	switch b {
+	case CurrentWeight:
+		if current > 0 {
+			return current
+		}
+		return h.Latest()
+	case EventWeight:
+		if e.Weight.Present {
+			return e.Weight.Value
+		}
+	case MedianWeight:
+		return h.Median()
+	}
+	return 0
+}
+
+// withWeight is a copy of the event with its FTP and the W/kg for each duration worked
+// out from watts and weight. W/kg values without watts are kept as reported.
+func (e Event) withWeight(weight float64, durations []PowerDuration) Event {
+	if weight <= 0 {
+		return e
+	}
+
+	if e.WFtp.Present {
+		e.WkgFtp = PowerValue{Value: e.WFtp.Value / weight, Present: true}
+	}
+
+	values := make(map[string]PowerValue, len(e.values))
+	for k, v := range e.values {
+		values[k] = v
+	}
+	for _, d := range durations {
+		if w := e.Watts(d); w.Present {
+			values[d.WkgKey] = PowerValue{Value: w.Value / weight, Present: true}
+		}
+	}
+	e.values = values
+	return e
+}
+
+// ParseWeightBasis reads "reported", "current", "event" or "median"
+func ParseWeightBasis(s string) (WeightBasis, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "reported":
+		return ReportedWeight, nil
+	case "current":
+		return CurrentWeight, nil
+	case "event":
+		return EventWeight, nil
+	case "median":
+		return MedianWeight, nil
+	}
+	return ReportedWeight, fmt.Errorf("unknown weight basis %q: expected reported, current, event or median", s)
+}
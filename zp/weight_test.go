@@ -0,0 +1,115 @@
+package zp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWeightAnomalies(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, 3, d, 18, 0, 0, 0, time.UTC) }
+	point := func(d int, kg float64, race bool) WeightPoint {
+		return WeightPoint{Weight: kg, Race: race, Event: EventRef{ID: ID(day(d).Format("0102")), Title: "Event", Date: day(d)}}
+	}
+
+	h := WeightHistory{
+		point(1, 72, false),
+		point(3, 70.5, true),  // Only 1.5kg lighter
+		point(8, 69.9, true),  // 2.1kg lighter than a week ago
+		point(10, 69, false),  // Lighter, but not a race
+		point(20, 70, false),  // Put it back on again
+		point(23, 67, true),   // 3kg lighter than three days ago
+		point(30, 67.9, true), // Only 2.1kg lighter than anything in the last two weeks
+	}
+	if h.Min() != 67 || h.Max() != 72 || h.Latest() != 67.9 || h.Median() != 69.9 {
+		t.Errorf("got min %v, max %v, latest %v and median %v", h.Min(), h.Max(), h.Latest(), h.Median())
+	}
+
+	got := DefaultWeightCheck.Anomalies(h)
+	if len(got) != 3 {
+		t.Fatalf("got %d anomalies, expected 3: %v", len(got), got)
+	}
+	expected := []struct {
+		before, race string
+		drop         float64
+	}{
+		{"0301", "0308", 2.1},
+		{"0320", "0323", 3},
+		{"0320", "0330", 2.1},
+	}
+	for i, e := range expected {
+		if string(got[i].Before.Event.ID) != e.before || string(got[i].Race.Event.ID) != e.race || math.Abs(got[i].Drop()-e.drop) > 1e-9 {
+			t.Errorf("anomaly %d: got %s after %s, expected a drop of %.1fkg at %s after %s", i, got[i], got[i].Before.Event.ID, e.drop, e.race, e.before)
+		}
+	}
+	if got[1].String() != "-3.0kg in Event (2021-03-23)" {
+		t.Errorf("got %q", got[1].String())
+	}
+
+	if got := (WeightCheck{Drop: 2, Days: 1}).Anomalies(h); len(got) != 0 {
+		t.Errorf("expected no anomalies within a day, got %v", got)
+	}
+	if got := (WeightCheck{}).Anomalies(h); len(got) != 0 {
+		t.Errorf("expected no anomalies without a drop, got %v", got)
+	}
+	if (WeightHistory{}).Min() != 0 || (WeightHistory{}).Median() != 0 {
+		t.Errorf("expected 0 from an empty history")
+	}
+}
+
+func TestWithWeight(t *testing.T) {
+	var e Event
+	if err := e.UnmarshalJSON([]byte(testevent)); err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+	ds := Durations(20*time.Minute, 5*time.Second)
+
+	reweighed := e.withWeight(50, ds)
+	if reweighed.WkgFtp.Value != 2 || reweighed.Wkg(ds[0]).Value != 106.0/50 || reweighed.Wkg(ds[1]).Value != 3 {
+		t.Errorf("got FTP %v, 20 minutes %v and 5 seconds %v", reweighed.WkgFtp.Value, reweighed.Wkg(ds[0]).Value, reweighed.Wkg(ds[1]).Value)
+	}
+	if e.WkgFtp.Value != 1.7 || e.Wkg(ds[0]).Value != 1.9 {
+		t.Errorf("original event changed: FTP %v, 20 minutes %v", e.WkgFtp.Value, e.Wkg(ds[0]).Value)
+	}
+	if e.withWeight(0, ds).Wkg(ds[0]).Value != 1.9 {
+		t.Errorf("expected no weight to leave the W/kg alone")
+	}
+}
+
+func TestParseWeightBasis(t *testing.T) {
+	for s, expected := range map[string]WeightBasis{"": ReportedWeight, "reported": ReportedWeight, "Current": CurrentWeight, "event": EventWeight, " median ": MedianWeight} {
+		got, err := ParseWeightBasis(s)
+		if err != nil || got != expected {
+			t.Errorf("%q: got %v, %v, expected %v", s, got, err, expected)
+		}
+	}
+	if _, err := ParseWeightBasis("lightest"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestWeightBasis(t *testing.T) {
+	// Just after a race at 54kg with 156W for 20 minutes. A week before that was a race
+	// at 56.3kg with 157W.
+	asOf := time.Date(2020, 12, 3, 0, 0, 0, 0, time.UTC)
+	d := Duration(20 * 60)
+	cases := []struct {
+		basis    WeightBasis
+		expected float64 // Best 20 minute W/kg in the last 7 days
+	}{
+		{ReportedWeight, 2.8},
+		{EventWeight, 156 / 54.0},
+		{CurrentWeight, 157 / 54.0}, // Without a team list, the current weight is the latest
+		{MedianWeight, 157 / 56.3},
+	}
+	for _, tc := range cases {
+		c, _ := newFixtureClient(t, WithWindows(7), WithReferenceTime(asOf), WithWeightBasis(tc.basis))
+		r, err := c.ImportRider(5555)
+		if err != nil {
+			t.Fatalf("ImportRider: %v", err)
+		}
+		if got := r.PowerGroups[0].Wpkg[d.Seconds].Value; math.Abs(got-tc.expected) > 1e-9 {
+			t.Errorf("basis %d: got %v, expected %v", tc.basis, got, tc.expected)
+		}
+	}
+}
@@ -1,15 +1,14 @@
 package zp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
-	"net/http/cookiejar"
-	"net/url"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,9 +20,11 @@ type club struct {
 type Rider struct {
 	Name   string
 	Zwid   int
-	Weight interface{} `json:"w"`
-	Div    int         `json:"div"`  //ZP cat 5 = A+, 10 = A, 20 = B, 30 = C, 40 = D
-	DivW   int         `json:"divw"` //ZP womens car 5 = A+, 10 = A, 20 = B, 30 = C, 40 = D
+	Weight PowerValue `json:"w"`
+	Div    int        `json:"div"`  //ZP cat 5 = A+, 10 = A, 20 = B, 30 = C, 40 = D
+	DivW   int        `json:"divw"` //ZP womens car 5 = A+, 10 = A, 20 = B, 30 = C, 40 = D
+
+	RacingScore PowerValue `json:"zrs"` // Zwift racing score, if ZwiftPower has it
 }
 
 // Rider shows data about a rider
@@ -60,62 +61,125 @@ type RiderDetail struct {
 	// W15sec30Days float64
 	// W5sec30Days  float64
 
-	Power30Days riderPowerGroup
-	Power42Days riderPowerGroup
-	Power60Days riderPowerGroup
-	Power90Days riderPowerGroup
+	// PowerGroups holds the rider's best efforts in each of the client's event sets over
+	// each of its periods, in the same order, e.g. all the periods for races, then all
+	// the periods for TTs
+	PowerGroups []riderPowerGroup
 
 	Weight float64
 
+	// WeightHistory is the rider's weight in each of their events, and WeightAnomalies
+	// are the races where they were suddenly lighter
+	WeightHistory   WeightHistory
+	WeightAnomalies []WeightAnomaly
+
+	// Trend is how the rider's form has been changing recently
+	Trend Trend
+
+	// Category is the category the rider's recent races put them in, using the women's
+	// rules for riders in a women's category
+	Category CategoryStatus
+
 	Div  int //ZP cat 5 = A+, 10 = A, 20 = B, 30 = C, 40 = D
 	DivW int //ZP womens car 5 = A+, 10 = A, 20 = B, 30 = C, 40 = D
 
+	// RacingScore is the rider's Zwift racing score from the team list, or else from
+	// their latest event that has one
+	RacingScore PowerValue
 }
 
 type riderEvents struct {
 	Data []Event
 }
 type riderPowerGroup struct {
-	TimePeriod int
-	Races      int
-	FTP        float64
-	Watts      riderPower
-	Wpkg       riderPower
-}
-type riderPower struct {
-	Min20 float64
-	Min5  float64
-	Min2  float64
-	Min1  float64
-	Sec30 float64
-	Sec15 float64
-	Sec5  float64
+	Events EventSet
+	Period Period
+	Races  int
+	FTP    Best
+	Watts  riderPower
+	Wpkg   riderPower
+
+	// Every event's values, for working out statistics such as the median
+	FTPs         []float64
+	WattsEfforts efforts
+	WpkgEfforts  efforts
 }
 
+// riderPower holds the best power for each duration, keyed by the duration in seconds
+type riderPower map[int]Best
+
 // Event is a ZwiftPower event
 type Event struct {
 	EventType     string        `json:"f_t"`
 	EventDateSecs EventDateType `json:"event_date"`
 	EventDate     time.Time
-	EventTitle    string      `json:"event_title"`
-	AvgWkg        interface{} `json:"avg_wkg"`
-	WkgFtp        interface{} `json:"wkg_ftp"`
-	Wkg20min      interface{} `json:"wkg1200"`
-	Wkg5min       interface{} `json:"wkg300"`
-	Wkg2min       interface{} `json:"wkg120"`
-	Wkg1min       interface{} `json:"wkg60"`
-	Wkg30sec      interface{} `json:"wkg30"`
-	Wkg15sec      interface{} `json:"wkg15"`
-	Wkg5sec       interface{} `json:"wkg5"`
-	WFtp          interface{} `json:"wftp"`
-	W20min        interface{} `json:"w1200"`
-	W5min         interface{} `json:"w300"`
-	W2min         interface{} `json:"w120"`
-	W1min         interface{} `json:"w60"`
-	W30sec        interface{} `json:"w30"`
-	W15sec        interface{} `json:"w15"`
-	W5sec         interface{} `json:"w5"`
-	Weight        interface{} `json:"weight"`
+	EventTitle    string     `json:"event_title"`
+	AvgWkg        PowerValue `json:"avg_wkg"`
+	WkgFtp        PowerValue `json:"wkg_ftp"`
+	Wkg20min      PowerValue `json:"wkg1200"`
+	Wkg5min       PowerValue `json:"wkg300"`
+	Wkg2min       PowerValue `json:"wkg120"`
+	Wkg1min       PowerValue `json:"wkg60"`
+	Wkg30sec      PowerValue `json:"wkg30"`
+	Wkg15sec      PowerValue `json:"wkg15"`
+	Wkg5sec       PowerValue `json:"wkg5"`
+	WFtp          PowerValue `json:"wftp"`
+	W20min        PowerValue `json:"w1200"`
+	W5min         PowerValue `json:"w300"`
+	W2min         PowerValue `json:"w120"`
+	W1min         PowerValue `json:"w60"`
+	W30sec        PowerValue `json:"w30"`
+	W15sec        PowerValue `json:"w15"`
+	W5sec         PowerValue `json:"w5"`
+	Weight        PowerValue `json:"weight"`
+	Height        PowerValue `json:"height"`
+
+	EventID       ID         `json:"zid"`    // The event's ID on ZwiftPower
+	ResultID      ID         `json:"res_id"` // This rider's result within the event
+	Zwid          int        `json:"zwid"`
+	Category      string     `json:"category"` // The category raced, e.g. "B"
+	Position      PowerValue `json:"pos"`
+	PositionInCat PowerValue `json:"position_in_cat"`
+	Time          PowerValue `json:"time"`     // Seconds to finish
+	Gap           PowerValue `json:"gap"`      // Seconds behind the winner
+	Distance      PowerValue `json:"distance"` // Kilometres
+	Laps          PowerValue `json:"laps"`
+	Duration      PowerValue `json:"dur"` // Seconds, for events with a fixed duration rather than distance
+	RouteID       ID         `json:"rt"`
+	WorldID       ID         `json:"map"` // The Zwift world the route is in
+	AvgHR         PowerValue `json:"avg_hr"`
+	MaxHR         PowerValue `json:"max_hr"`
+	AvgPower      PowerValue `json:"avg_power"` // Watts
+	NP            PowerValue `json:"np"`        // Normalized power, in watts
+	RacingScore   PowerValue `json:"zrs"`       // The rider's Zwift racing score at the time
+	TeamID        ID         `json:"tid"`
+	TeamName      string     `json:"tname"`
+	Penalty       ID         `json:"penalty"` // Set if the result was penalised or disqualified
+	Upgraded      PowerValue `json:"upg"`     // 1 if the rider was upgraded on the strength of this result
+
+	// values holds every field of the event that looks like a number, so that we can
+	// find the power for any duration by its key
+	values map[string]PowerValue
+}
+
+// UnmarshalJSON decodes the event's fields as usual, and also keeps all of its values by key
+func (e *Event) UnmarshalJSON(data []byte) error {
+	type event Event // without this method, so we don't recurse
+	if err := json.Unmarshal(data, (*event)(e)); err != nil {
+		return err
+	}
+	e.values = nil
+	return json.Unmarshal(data, &e.values)
+}
+
+// Disqualified reports whether ZwiftPower penalised or disqualified this result
+func (e Event) Disqualified() bool {
+	return e.Penalty != "" && e.Penalty != "0"
+}
+
+// RaceTime is how long the rider took to finish
+func (e Event) RaceTime() time.Duration {
+	return time.Duration(e.Time.Value * float64(time.Second))
 }
 
 // EventDateType so we can use a custom unmarshaller
@@ -132,117 +196,108 @@ func (e *EventDateType) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-var (
-	CloudFrontPolicy    string
-	CloudFrontSignature string
-	CloudFrontKeyPairId string
-)
-
-const zpTeamURL = "https://zwiftpower.com/cache3/teams/%d_riders.json"
-const zpRiderURL = "https://zwiftpower.com/cache3/profile/%d_all.json"
+// ImportTeam imports data about the team with this ID. If some riders' data can't be
+// imported, it returns the rest of the team along with an *ImportError listing the failures.
+func (c *Client) ImportTeam(clubID int, limit int) ([]RiderDetail, error) {
+	return c.ImportTeamContext(context.Background(), clubID, limit)
+}
 
-func newClient() (*http.Client, error) {
-	log.Printf("NewClient")
-	jar, err := cookiejar.New(nil)
+// ImportTeamContext is ImportTeam with a context. If ctx is cancelled, riders that are
+// being fetched or still waiting to be fetched are abandoned and ctx's error is returned.
+func (c *Client) ImportTeamContext(ctx context.Context, clubID int, limit int) ([]RiderDetail, error) {
+	var cl club
+	_, err := c.getJSON(ctx, c.teamURL(clubID), &cl)
 	if err != nil {
-		return nil, err
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusForbidden && statusErr.Err == nil {
+			// Team lists aren't private, so we must be the problem
+			statusErr.Err = ErrAuthExpired
+		}
+		return nil, fmt.Errorf("getting club data: %w", err)
 	}
 
-	/*phpbb3_lswlk_u=57526;
-	phpbb3_lswlk_k=ec01eab84919a3dd;
-	phpbb3_lswlk_sid=cdeaf271fcffbdf0be7499e33193d133*/
-
-	//jar.SetCookies()
-	// req.AddCookie(&http.Cookie{Name: "CloudFront-Policy", Value: CloudFrontPolicy})
-	// req.AddCookie(&http.Cookie{Name: "CloudFront-Signature", Value: CloudFrontSignature})
-	// req.AddCookie(&http.Cookie{Name: "CloudFront-Key-Pair-Id", Value: CloudFrontKeyPairId})
-
-	var cookies []*http.Cookie
-
-	cookies = append(cookies, &http.Cookie{
-		Name:   "CloudFront-Policy",
-		Value:  CloudFrontPolicy,
-		Path:   "/",
-		Domain: "zwiftpower.com",
-	})
-
-	cookies = append(cookies, &http.Cookie{
-		Name:   "CloudFront-Signature",
-		Value:  CloudFrontSignature,
-		Path:   "/",
-		Domain: "zwiftpower.com",
-	})
-
-	cookies = append(cookies, &http.Cookie{
-		Name:   "CloudFront-Key-Pair-Id",
-		Value:  CloudFrontKeyPairId,
-		Path:   "/",
-		Domain: "zwiftpower.com",
-	})
-
-	u, _ := url.Parse("https://zwiftpower.com")
-	jar.SetCookies(u, cookies)
-
-	client := &http.Client{
-		Jar: jar,
+	riders := cl.Riders
+	if limit > 0 && len(riders) > limit {
+		log.Printf("Limiting output to %d riders", limit)
+		riders = riders[:limit]
 	}
 
-	return client, nil
-}
-
-// ImportTeam imports data about the team with this ID
-func ImportTeam(clubID int, limit int) ([]RiderDetail, error) {
-	client, err := newClient()
-	if err != nil {
-		return nil, fmt.Errorf("error getting client: %v", err)
+	details, errs := c.importRiders(ctx, riders)
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	data, err := getJSON(client, fmt.Sprintf(zpTeamURL, clubID))
-	if err != nil {
-		return nil, fmt.Errorf("getting club data: %v", err)
+	// Carry on without any riders we couldn't get, and tell the caller who they were
+	output := make([]RiderDetail, 0, len(details))
+	importErr := &ImportError{Riders: len(riders)}
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("Skipping %s (%d): %v", riders[i].Name, riders[i].Zwid, err)
+			importErr.Failures = append(importErr.Failures, &RiderError{
+				Zwid: riders[i].Zwid,
+				Name: strings.TrimSpace(riders[i].Name),
+				Err:  err,
+			})
+			continue
+		}
+		output = append(output, details[i])
 	}
 
-	var c club
-	err = json.Unmarshal(data, &c)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshalling club data: %v", err)
+	if len(importErr.Failures) > 0 {
+		return output, importErr
 	}
+	return output, nil
+}
 
-	//return c.Data, nil
-	output := make([]RiderDetail, len(c.Riders))
-
-	for i, rider := range c.Riders {
-		var err error
-
-		riderDetail, err := importRider(client, rider)
-		if err != nil {
-			return nil, fmt.Errorf("loading data for %s (%d): %v", rider.Name, rider.Zwid, err)
-		}
-		output[i] = riderDetail
+// importRiders fetches the riders' profiles using a pool of workers. The results and
+// errors are in the same order as riders.
+func (c *Client) importRiders(ctx context.Context, riders []Rider) ([]RiderDetail, []error) {
+	output := make([]RiderDetail, len(riders))
+	errs := make([]error, len(riders))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < c.concurrency && w < len(riders); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				output[i], errs[i] = c.importRider(ctx, riders[i])
+			}
+		}()
+	}
 
-		if limit > 0 && i >= (limit-1) {
-			log.Printf("Limiting output to %d riders", limit)
-			break
+queue:
+	for i := range riders {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			for ; i < len(riders); i++ {
+				errs[i] = ctx.Err()
+			}
+			break queue
 		}
 	}
+	close(jobs)
+	wg.Wait()
 
-	return output, nil
+	return output, errs
 }
-func ImportRider(riderID int) (riderDetail RiderDetail, err error) {
+
+// ImportRider imports data about the rider with this ID
+func (c *Client) ImportRider(riderID int) (riderDetail RiderDetail, err error) {
+	return c.ImportRiderContext(context.Background(), riderID)
+}
+
+// ImportRiderContext is ImportRider with a context
+func (c *Client) ImportRiderContext(ctx context.Context, riderID int) (riderDetail RiderDetail, err error) {
 	var rider Rider
 	rider.Zwid = riderID
 
-	client, err := newClient()
-	if err != nil {
-		log.Printf("error getting client: %v", err)
-		return riderDetail, err
-	}
-
-	return importRider(client, rider)
+	return c.importRider(ctx, rider)
 }
 
-// ImportRider imports data about the rider with this ID
-func importRider(client *http.Client, rider Rider) (riderDetail RiderDetail, err error) {
+func (c *Client) importRider(ctx context.Context, rider Rider) (riderDetail RiderDetail, err error) {
 	// I think hitting the profile URL loads the data into the cache
 	log.Printf("ImportRider(%d)", rider.Zwid)
 
@@ -250,57 +305,60 @@ func importRider(client *http.Client, rider Rider) (riderDetail RiderDetail, err
 	riderDetail.Name = strings.TrimSpace(rider.Name)
 	riderDetail.Div = rider.Div
 	riderDetail.DivW = rider.DivW
-	riderDetail.Weight = convertPowerValue(rider.Weight)
-	riderDetail.Power30Days.TimePeriod = 30
-	riderDetail.Power42Days.TimePeriod = 42
-	riderDetail.Power60Days.TimePeriod = 60
-	riderDetail.Power90Days.TimePeriod = 90
-
-	data, err := getJSON(client, fmt.Sprintf(zpRiderURL, rider.Zwid))
-	if err != nil {
-		log.Printf("loading data for %s (%d): %v", rider.Name, rider.Zwid, err)
-		return riderDetail, err
+	riderDetail.Weight = rider.Weight.Value
+	riderDetail.RacingScore = rider.RacingScore
+	for _, set := range c.eventSets {
+		for _, p := range c.periods {
+			riderDetail.PowerGroups = append(riderDetail.PowerGroups, riderPowerGroup{Events: set, Period: p})
+		}
 	}
 
-	var r riderEvents
-	err = json.Unmarshal(data, &r)
+	events, err := c.fetchEvents(ctx, rider.Zwid)
 	if err != nil {
-		log.Printf("Error unmarshalling data: %v", err)
-		log.Printf(string(data))
+		log.Printf("loading data for %s (%d): %v", rider.Name, rider.Zwid, err)
 		return riderDetail, err
 	}
 
 	riderDetail.Zwid = rider.Zwid
-	if len(r.Data) < 1 {
+	if len(events) < 1 {
 		log.Printf("No event data for rider %d", rider.Zwid)
 		return riderDetail, nil
 	}
 
 	var latestEventDate time.Time
 	var latestRaceDate time.Time
-	for _, e := range r.Data {
-		e.EventDate = time.Unix(int64(e.EventDateSecs), 0)
-		daysAgo := int(time.Now().Sub(e.EventDate).Hours() / 24)
+	var latestScoreDate time.Time
+	now := c.clock.Now()
+	riderDetail.WeightHistory = weightHistory(events, c.classify, now)
+	riderDetail.WeightAnomalies = c.weightCheck.Anomalies(riderDetail.WeightHistory)
+	for _, e := range events {
+		if e.EventDate.After(now) {
+			// This happened after the time we're reporting as of, so it doesn't count
+			// towards the totals, latest dates or racing score either
+			continue
+		}
+
 		// log.Printf("date %v, from %v is %d days ago\n", e.EventDate, e.EventDateSecs, daysAgo)
-		isRace := strings.Contains(e.EventType, "RACE")
+		class := c.classify(e)
+		isRace := Races.Includes(class)
 
 		//if daysAgo <= 365 {
 		riderDetail.Rides++
 		if isRace {
 			riderDetail.Races++
-
-			processPowerGroup(&riderDetail.Power30Days, e, daysAgo)
-			processPowerGroup(&riderDetail.Power42Days, e, daysAgo)
-			processPowerGroup(&riderDetail.Power60Days, e, daysAgo)
-			processPowerGroup(&riderDetail.Power90Days, e, daysAgo)
+		}
+		weight := c.weightBasis.weightFor(e, riderDetail.Weight, riderDetail.WeightHistory)
+		reweighed := e.withWeight(weight, c.durations)
+		for i := range riderDetail.PowerGroups {
+			processPowerGroup(&riderDetail.PowerGroups[i], reweighed, class, now, c.durations)
 		}
 		//}
 
 		// var eventWkgFtp float64
 		// var eventAvgWkg float64
 
-		// eventWkgFtp = convertPowerValue(e.WkgFtp)
-		// eventAvgWkg = convertPowerValue(e.AvgWkg)
+		// eventWkgFtp = e.WkgFtp.Value
+		// eventAvgWkg = e.AvgWkg.Value
 
 		// // Last three months?
 		// if daysAgo <= 90 {
@@ -334,32 +392,32 @@ func importRider(client *http.Client, rider Rider) (riderDetail RiderDetail, err
 		// 	// }
 
 		// 	// 20 min power
-		// 	replaceIfGreater(&riderDetail.W20min30Days, convertPowerValue(e.W20min))
-		// 	replaceIfGreater(&riderDetail.Wkg20min30Days, convertPowerValue(e.Wkg20min))
+		// 	replaceIfGreater(&riderDetail.W20min30Days, e.W20min.Value)
+		// 	replaceIfGreater(&riderDetail.Wkg20min30Days, e.Wkg20min.Value)
 
 		// 	// 5 minute power
-		// 	replaceIfGreater(&riderDetail.W5min30Days, convertPowerValue(e.W5min))
-		// 	replaceIfGreater(&riderDetail.Wkg5min30Days, convertPowerValue(e.Wkg5min))
+		// 	replaceIfGreater(&riderDetail.W5min30Days, e.W5min.Value)
+		// 	replaceIfGreater(&riderDetail.Wkg5min30Days, e.Wkg5min.Value)
 
 		// 	// 2 minute power
-		// 	replaceIfGreater(&riderDetail.W2min30Days, convertPowerValue(e.W2min))
-		// 	replaceIfGreater(&riderDetail.Wkg2min30Days, convertPowerValue(e.Wkg2min))
+		// 	replaceIfGreater(&riderDetail.W2min30Days, e.W2min.Value)
+		// 	replaceIfGreater(&riderDetail.Wkg2min30Days, e.Wkg2min.Value)
 
 		// 	// 1 minute power
-		// 	replaceIfGreater(&riderDetail.W1min30Days, convertPowerValue(e.W1min))
-		// 	replaceIfGreater(&riderDetail.Wkg1min30Days, convertPowerValue(e.Wkg1min))
+		// 	replaceIfGreater(&riderDetail.W1min30Days, e.W1min.Value)
+		// 	replaceIfGreater(&riderDetail.Wkg1min30Days, e.Wkg1min.Value)
 
 		// 	// 30 second power
-		// 	replaceIfGreater(&riderDetail.W30sec30Days, convertPowerValue(e.W30sec))
-		// 	replaceIfGreater(&riderDetail.Wkg30sec30Days, convertPowerValue(e.Wkg30sec))
+		// 	replaceIfGreater(&riderDetail.W30sec30Days, e.W30sec.Value)
+		// 	replaceIfGreater(&riderDetail.Wkg30sec30Days, e.Wkg30sec.Value)
 
 		// 	// 15 second power
-		// 	replaceIfGreater(&riderDetail.W15sec30Days, convertPowerValue(e.W15sec))
-		// 	replaceIfGreater(&riderDetail.Wkg15sec30Days, convertPowerValue(e.Wkg15sec))
+		// 	replaceIfGreater(&riderDetail.W15sec30Days, e.W15sec.Value)
+		// 	replaceIfGreater(&riderDetail.Wkg15sec30Days, e.Wkg15sec.Value)
 
 		// 	// 5 second power
-		// 	replaceIfGreater(&riderDetail.W5sec30Days, convertPowerValue(e.W5sec))
-		// 	replaceIfGreater(&riderDetail.Wkg5sec30Days, convertPowerValue(e.Wkg5sec))
+		// 	replaceIfGreater(&riderDetail.W5sec30Days, e.W5sec.Value)
+		// 	replaceIfGreater(&riderDetail.Wkg5sec30Days, e.Wkg5sec.Value)
 		// }
 
 		if e.EventDate.After(latestEventDate) {
@@ -367,101 +425,76 @@ func importRider(client *http.Client, rider Rider) (riderDetail RiderDetail, err
 			riderDetail.LatestEvent = e.EventTitle
 		}
 
+		if !rider.RacingScore.Present && e.RacingScore.Present && e.EventDate.After(latestScoreDate) {
+			latestScoreDate = e.EventDate
+			riderDetail.RacingScore = e.RacingScore
+		}
+
 		if isRace && e.EventDate.After(latestRaceDate) {
 			latestRaceDate = e.EventDate
 			riderDetail.LatestRace = e.EventTitle
-			riderDetail.LatestRaceAvgWkg = convertPowerValue(e.AvgWkg)
-			riderDetail.LatestRaceWkgFtp = convertPowerValue(e.WkgFtp)
+			riderDetail.LatestRaceAvgWkg = e.AvgWkg.Value
+			riderDetail.LatestRaceWkgFtp = e.WkgFtp.Value
 		}
 	}
 
 	riderDetail.LatestEventDate = latestEventDate
 	riderDetail.LatestRaceDate = latestRaceDate
+
+	analyser := c.trend
+	if analyser.Classify == nil {
+		analyser.Classify = c.classify
+	}
+	riderDetail.Trend = analyser.Analyse(events, now)
+
+	engine := c.categories
+	if engine.Classify == nil {
+		engine.Classify = c.classify
+	}
+	riderDetail.Category = engine.Categorise(events, riderDetail.Weight, riderDetail.DivW != 0, now)
 	return riderDetail, nil
 }
 
-func processPowerGroup(powerGroup *riderPowerGroup, event Event, daysAgo int) {
-	if daysAgo <= powerGroup.TimePeriod {
+func processPowerGroup(powerGroup *riderPowerGroup, event Event, class EventClass, now time.Time, durations []PowerDuration) {
+	if powerGroup.Events.Includes(class) && powerGroup.Period.Contains(event.EventDate, now) {
 		powerGroup.Races++
 
-		replaceIfGreater(&powerGroup.FTP, convertPowerValue(event.WkgFtp))
-
-		// 20 min power
-		replaceIfGreater(&powerGroup.Watts.Min20, convertPowerValue(event.W20min))
-		replaceIfGreater(&powerGroup.Wpkg.Min20, convertPowerValue(event.Wkg20min))
-
-		// 5 minute power
-		replaceIfGreater(&powerGroup.Watts.Min5, convertPowerValue(event.W5min))
-		replaceIfGreater(&powerGroup.Wpkg.Min5, convertPowerValue(event.Wkg5min))
-
-		// 2 minute power
-		replaceIfGreater(&powerGroup.Watts.Min2, convertPowerValue(event.W2min))
-		replaceIfGreater(&powerGroup.Wpkg.Min2, convertPowerValue(event.Wkg2min))
+		powerGroup.FTP.replaceIfGreater(event.WkgFtp.Value, event)
 
-		// 1 minute power
-		replaceIfGreater(&powerGroup.Watts.Min1, convertPowerValue(event.W1min))
-		replaceIfGreater(&powerGroup.Wpkg.Min1, convertPowerValue(event.Wkg1min))
-
-		// 30 second power
-		replaceIfGreater(&powerGroup.Watts.Sec30, convertPowerValue(event.W30sec))
-		replaceIfGreater(&powerGroup.Wpkg.Sec30, convertPowerValue(event.Wkg30sec))
-
-		// 15 second power
-		replaceIfGreater(&powerGroup.Watts.Sec15, convertPowerValue(event.W15sec))
-		replaceIfGreater(&powerGroup.Wpkg.Sec15, convertPowerValue(event.Wkg15sec))
-
-		// 5 second power
-		replaceIfGreater(&powerGroup.Watts.Sec5, convertPowerValue(event.W5sec))
-		replaceIfGreater(&powerGroup.Wpkg.Sec5, convertPowerValue(event.Wkg5sec))
-	}
-}
+		if event.WkgFtp.Present && event.WkgFtp.Value > 0 {
+			powerGroup.FTPs = append(powerGroup.FTPs, event.WkgFtp.Value)
+		}
 
-func convertPowerValue(sourceVal interface{}) float64 {
-	sourceValArr := sourceVal.([]interface{})
-	pwrVal, ok := sourceValArr[0].(float64) // try to convert as float64
-	if !ok {
-		// if float 64 fails then try as string
-		var err error
-		pwrVal, err = strconv.ParseFloat(sourceValArr[0].(string), 64)
-		if err != nil {
-			//log.Fatal(err)
+		if powerGroup.Watts == nil {
+			powerGroup.Watts = riderPower{}
+			powerGroup.Wpkg = riderPower{}
+			powerGroup.WattsEfforts = efforts{}
+			powerGroup.WpkgEfforts = efforts{}
+		}
+		for _, d := range durations {
+			powerGroup.Watts.replaceIfGreater(d, event.Watts(d).Value, event)
+			powerGroup.Wpkg.replaceIfGreater(d, event.Wkg(d).Value, event)
+			powerGroup.WattsEfforts.add(d, event.Watts(d))
+			powerGroup.WpkgEfforts.add(d, event.Wkg(d))
 		}
 	}
-	return pwrVal
 }
 
-func replaceIfGreater(current *float64, newVal float64) {
-	if newVal > *current {
-		*current = newVal
+// PowerGroup returns the rider's power group for the event set and period with the same
+// names as set and p
+func (r RiderDetail) PowerGroup(set EventSet, p Period) (riderPowerGroup, bool) {
+	for _, g := range r.PowerGroups {
+		if g.Period != nil && g.Events.Name == set.Name && g.Period.Label() == p.Label() {
+			return g, true
+		}
 	}
+	return riderPowerGroup{Events: set, Period: p}, false
 }
 
-func getJSON(client *http.Client, url string) ([]byte, error) {
-	//resp, err := client.Get(url)
-	req, err := http.NewRequest("GET", url, nil)
-
-	if err != nil {
-		return []byte{}, err
-	}
-
-	// req.AddCookie(&http.Cookie{Name: "CloudFront-Policy", Value: CloudFrontPolicy})
-	// req.AddCookie(&http.Cookie{Name: "CloudFront-Signature", Value: CloudFrontSignature})
-	// req.AddCookie(&http.Cookie{Name: "CloudFront-Key-Pair-Id", Value: CloudFrontKeyPairId})
-
-	resp, err := client.Do(req)
-
-	if err != nil {
-		return []byte{}, err
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return []byte{}, fmt.Errorf("unexpected status %d for %s", resp.StatusCode, url)
-	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	return body, err
+func (p riderPower) replaceIfGreater(d PowerDuration, newVal float64, e Event) {
+	best := p[d.Seconds]
+	best.replaceIfGreater(newVal, e)
+	p[d.Seconds] = best
 }
 
 // // MonthsAgo describes how many months since the rider's latest event
@@ -488,178 +521,3 @@ func getJSON(client *http.Client, url string) ([]byte, error) {
 // 		return fmt.Sprintf("%d months ago", monthDiff)
 // 	}
 // }
-
-// Strings turns a rider struct into []string headers
-func ColumnHeaders() []string {
-	powerGrpStart := 6
-	powerGrps := 4
-	powerGrpLength := 16
-
-	output := make([]string, 6+(powerGrps*powerGrpLength))
-	output[0] = "Name"
-	output[1] = "Zwid"
-	output[2] = "Profile"
-	output[3] = "Category"
-	output[4] = "Womens Category"
-	output[5] = "Weight"
-
-	addPowerGroupHeaders(&output, "30", powerGrpStart)
-	addPowerGroupHeaders(&output, "42", powerGrpStart+powerGrpLength)
-	addPowerGroupHeaders(&output, "60", powerGrpStart+(2*powerGrpLength))
-	addPowerGroupHeaders(&output, "90", powerGrpStart+(3*powerGrpLength))
-	// output[5] = "30Days"
-	// output[6] = "60Days"
-	// output[7] = "90Days"
-	// output[8] = "Races30Days"
-	// output[9] = "Races90Days"
-
-	// output[10] = "W20min30Days"
-	// output[11] = "Wkg20min30Days"
-
-	// output[12] = "W5min30Days"
-	// output[13] = "Wkg5min30Days"
-
-	// output[14] = "W2min30Days"
-	// output[15] = "Wkg2min30Days"
-
-	// output[16] = "W1min30Days"
-	// output[17] = "Wkg1min30Days"
-
-	// output[18] = "W30sec30Days"
-	// output[19] = "Wkg30sec30Days"
-
-	// output[20] = "W15sec30Days"
-	// output[21] = "Wkg15sec30Days"
-
-	// output[22] = "W5sec30Days"
-	// output[23] = "Wkg5sec30Days"
-
-	//output[24] = "Weight"
-
-	return output
-}
-
-func addPowerGroupHeaders(output *[]string, days string, i int) {
-	baseString := fmt.Sprintf("%sDays", days)
-	(*output)[i] = fmt.Sprintf("Races%s", baseString)
-	(*output)[i+1] = fmt.Sprintf("FTP%s", baseString)
-
-	(*output)[i+2] = fmt.Sprintf("W20Min%s", baseString)
-	(*output)[i+3] = fmt.Sprintf("Wpkg20Min%s", baseString)
-
-	(*output)[i+4] = fmt.Sprintf("W5Min%s", baseString)
-	(*output)[i+5] = fmt.Sprintf("Wpkg5Min%s", baseString)
-
-	(*output)[i+6] = fmt.Sprintf("W2Min%s", baseString)
-	(*output)[i+7] = fmt.Sprintf("Wpkg2Min%s", baseString)
-
-	(*output)[i+8] = fmt.Sprintf("W1Min%s", baseString)
-	(*output)[i+9] = fmt.Sprintf("Wpkg1Min%s", baseString)
-
-	(*output)[i+10] = fmt.Sprintf("W30Sec%s", baseString)
-	(*output)[i+11] = fmt.Sprintf("Wpkg30Sec%s", baseString)
-
-	(*output)[i+12] = fmt.Sprintf("W15Sec%s", baseString)
-	(*output)[i+13] = fmt.Sprintf("Wpkg15Sec%s", baseString)
-
-	(*output)[i+14] = fmt.Sprintf("W5Sec%s", baseString)
-	(*output)[i+15] = fmt.Sprintf("Wpkg5Sec%s", baseString)
-}
-
-func catValToString(cat int) (catString string) {
-	switch cat {
-	case 5:
-		catString = "A+"
-	case 10:
-		catString = "A"
-	case 20:
-		catString = "B"
-	case 30:
-		catString = "C"
-	case 40:
-		catString = "D"
-	default:
-		catString = ""
-	}
-	return
-}
-
-// Strings turns a rider struct into []string
-func (r RiderDetail) Strings() []string {
-	// the length of a rider row. This is the fixed fields (name, zwid, link, div, divw, weight) + (16 x power groups)
-	powerGrpStart := 6
-	powerGrps := 4
-	powerGrpLength := 16
-
-	output := make([]string, 6+(powerGrps*powerGrpLength))
-	output[0] = r.Name
-	output[1] = strconv.Itoa(r.Zwid)
-	output[2] = fmt.Sprintf("https://zwiftpower.com/profile.php?z=%d", r.Zwid)
-	output[3] = catValToString(r.Div)
-	output[4] = catValToString(r.DivW)
-	output[5] = strconv.FormatFloat(r.Weight, 'f', 1, 64)
-
-	// output[5] = strconv.FormatFloat(r.Power30Days.FTP, 'f', 1, 64)
-	// output[6] = strconv.FormatFloat(r.Power42Days.FTP, 'f', 1, 64)
-	// output[7] = strconv.FormatFloat(r.Power60Days.FTP, 'f', 1, 64)
-	// output[7] = strconv.FormatFloat(r.Power90Days.FTP, 'f', 1, 64)
-
-	// output[8] = strconv.Itoa(r.Power30Days.Races)
-	// output[9] = strconv.Itoa(r.Power42Days.Races)
-	// output[8] = strconv.Itoa(r.Power60Days.Races)
-	// output[9] = strconv.Itoa(r.Power90Days.Races)
-
-	addPowerGroup(&output, r.Power30Days, powerGrpStart)
-	addPowerGroup(&output, r.Power42Days, powerGrpStart+powerGrpLength)
-	addPowerGroup(&output, r.Power60Days, powerGrpStart+(2*powerGrpLength))
-	addPowerGroup(&output, r.Power90Days, powerGrpStart+(3*powerGrpLength))
-
-	// output[10] = strconv.Itoa(int(r.W20min30Days))
-	// output[11] = strconv.FormatFloat(r.Wkg20min30Days, 'f', 1, 64)
-
-	// output[12] = strconv.Itoa(int(r.W5min30Days))
-	// output[13] = strconv.FormatFloat(r.Wkg5min30Days, 'f', 1, 64)
-
-	// output[14] = strconv.Itoa(int(r.W2min30Days))
-	// output[15] = strconv.FormatFloat(r.Wkg2min30Days, 'f', 1, 64)
-
-	// output[16] = strconv.Itoa(int(r.W1min30Days))
-	// output[17] = strconv.FormatFloat(r.Wkg1min30Days, 'f', 1, 64)
-
-	// output[18] = strconv.Itoa(int(r.W30sec30Days))
-	// output[19] = strconv.FormatFloat(r.Wkg30sec30Days, 'f', 1, 64)
-
-	// output[20] = strconv.Itoa(int(r.W15sec30Days))
-	// output[21] = strconv.FormatFloat(r.Wkg15sec30Days, 'f', 1, 64)
-
-	// output[22] = strconv.Itoa(int(r.W5sec30Days))
-	// output[23] = strconv.FormatFloat(r.Wkg5sec30Days, 'f', 1, 64)
-
-	return output
-}
-
-func addPowerGroup(output *[]string, powerGroup riderPowerGroup, i int) {
-	(*output)[i] = strconv.Itoa(powerGroup.Races)
-	(*output)[i+1] = strconv.FormatFloat(powerGroup.FTP, 'f', 1, 64)
-
-	(*output)[i+2] = strconv.Itoa(int(powerGroup.Watts.Min20))
-	(*output)[i+3] = strconv.FormatFloat(powerGroup.Wpkg.Min20, 'f', 1, 64)
-
-	(*output)[i+4] = strconv.Itoa(int(powerGroup.Watts.Min5))
-	(*output)[i+5] = strconv.FormatFloat(powerGroup.Wpkg.Min5, 'f', 1, 64)
-
-	(*output)[i+6] = strconv.Itoa(int(powerGroup.Watts.Min2))
-	(*output)[i+7] = strconv.FormatFloat(powerGroup.Wpkg.Min2, 'f', 1, 64)
-
-	(*output)[i+8] = strconv.Itoa(int(powerGroup.Watts.Min1))
-	(*output)[i+9] = strconv.FormatFloat(powerGroup.Wpkg.Min1, 'f', 1, 64)
-
-	(*output)[i+10] = strconv.Itoa(int(powerGroup.Watts.Sec30))
-	(*output)[i+11] = strconv.FormatFloat(powerGroup.Wpkg.Sec30, 'f', 1, 64)
-
-	(*output)[i+12] = strconv.Itoa(int(powerGroup.Watts.Sec15))
-	(*output)[i+13] = strconv.FormatFloat(powerGroup.Wpkg.Sec15, 'f', 1, 64)
-
-	(*output)[i+14] = strconv.Itoa(int(powerGroup.Watts.Sec5))
-	(*output)[i+15] = strconv.FormatFloat(powerGroup.Wpkg.Sec5, 'f', 1, 64)
-}